@@ -15,11 +15,19 @@ import (
 	"github.com/highdolen/L0/internal/database"
 	"github.com/highdolen/L0/internal/handlers"
 	"github.com/highdolen/L0/internal/kafka"
+	"github.com/highdolen/L0/internal/resp"
 	"github.com/highdolen/L0/internal/service"
 	"github.com/highdolen/L0/internal/web"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("Ошибка миграции: %v", err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 
 	// Загружаем конфиг
@@ -32,40 +40,102 @@ func main() {
 	}
 
 	// Подключение к базе
-	dsn := "postgres://" + cfg.DB.User + ":" + cfg.DB.Password + "@" + cfg.DB.Host + ":" + cfg.DB.Port + "/" + cfg.DB.Name + "?sslmode=" + cfg.DB.SSLMode
-	db, err := database.ConnectDB(dsn)
+	db, err := database.ConnectDB(cfg.DB.DSN())
 	if err != nil {
 		log.Fatalf("Ошибка подключения к БД: %v", err)
 	}
 	defer db.Close()
 
-	repo := database.NewOrderRepository(db)
+	// Схема БД: проверка или применение миграций (DB_MIGRATE)
+	if err := prepareSchema(ctx, db, cfg.DB.Migrate); err != nil {
+		log.Fatalf("Ошибка схемы БД: %v", err)
+	}
 
-	// Создаём кэш с TTL 30 минут
-	orderCache := cache.New(30 * time.Minute)
+	repo := database.NewOrderRepository(db)
 
 	// Создаём адаптеры для сервисного слоя
 	repoAdapter := service.NewRepositoryAdapter(repo)
-	cacheAdapter := service.NewCacheAdapter(orderCache)
+	cacheAdapter, err := newCacheService(cfg.Cache)
+	if err != nil {
+		log.Fatalf("Ошибка конфигурации кэша: %v", err)
+	}
+
+	// Журнал событий кэша для аудита
+	if cfg.Cache.EventLog {
+		events := cacheAdapter.Subscribe(0)
+		defer events.Close()
+		go logCacheEvents(events)
+	}
+
+	// Рассылка инвалидаций между репликами: сервис удаляет записи через обёртку,
+	// которая сообщает о них остальным, а события других реплик применяются
+	// к локальному кэшу напрямую
+	serviceCache := cacheAdapter
+	var ingestCache kafka.OrderCache = cacheAdapter
+	var invalidationBus *kafka.InvalidationBus
+	if cfg.Kafka.InvalidationTopic != "" {
+		if cfg.Cache.Backend == "redis" {
+			log.Println("Внешний кэш общий для всех реплик, рассылка инвалидаций не нужна")
+		} else {
+			log.Printf("Инвалидации кэша рассылаются через топик %s (узел %s)", cfg.Kafka.InvalidationTopic, cfg.Server.NodeID)
+			invalidationBus = kafka.NewInvalidationBus([]string{cfg.Kafka.Broker}, cfg.Kafka.InvalidationTopic, cfg.Server.NodeID, cacheAdapter)
+			serviceCache = service.NewBroadcastingCache(cacheAdapter, invalidationBus)
+			// Новые заказы из Kafka снимают у других реплик негативные записи
+			// и отметки полноты наборов по покупателю и track_number
+			ingestCache = service.NewIngestCache(cacheAdapter, invalidationBus)
+		}
+	}
 
 	// Создаём сервис заказов
-	orderService := service.NewOrderService(repoAdapter, cacheAdapter)
+	orderService := service.NewOrderService(repoAdapter, serviceCache)
 
-	// Загружаем данные из БД в кэш через адаптер
-	if err := orderService.LoadFromDB(ctx); err != nil {
-		log.Fatalf("Ошибка загрузки кэша: %v", err)
+	warmupMode, err := service.ParseWarmupMode(cfg.Warmup.Mode)
+	if err != nil {
+		log.Fatalf("Ошибка конфигурации прогрева кэша: %v", err)
 	}
-	log.Println("Кэш успешно загружен")
 
 	// Создаём Kafka Consumer
 	consumer := kafka.NewConsumer(
-		[]string{cfg.Kafka.Broker},
-		"orders",
-		"group-1",
+		kafka.ConsumerConfig{
+			Brokers:         []string{cfg.Kafka.Broker},
+			Topic:           cfg.Kafka.Topic,
+			GroupID:         cfg.Kafka.GroupID,
+			DeadLetterTopic: cfg.Kafka.DeadLetterTopic,
+			Retry: kafka.RetryPolicy{
+				MaxAttempts:    cfg.Kafka.RetryMaxAttempts,
+				InitialBackoff: cfg.Kafka.RetryInitialBackoff,
+				MaxBackoff:     cfg.Kafka.RetryMaxBackoff,
+			},
+			CommitInterval: cfg.Kafka.CommitInterval,
+			Workers:        cfg.Kafka.Workers,
+			BatchSize:      cfg.Kafka.BatchSize,
+			BatchTimeout:   cfg.Kafka.BatchTimeout,
+		},
 		repo,
-		orderCache,
+		ingestCache,
 	)
 
+	// Consumer событий изменения статуса заказов (если задан KAFKA_STATUS_TOPIC)
+	var statusConsumer *kafka.StatusConsumer
+	if cfg.Kafka.StatusTopic != "" {
+		log.Printf("Статусы заказов читаются из топика %s", cfg.Kafka.StatusTopic)
+		statusConsumer = kafka.NewStatusConsumer(
+			kafka.StatusConsumerConfig{
+				Brokers:         []string{cfg.Kafka.Broker},
+				Topic:           cfg.Kafka.StatusTopic,
+				GroupID:         cfg.Kafka.StatusGroupID,
+				DeadLetterTopic: cfg.Kafka.DeadLetterTopic,
+				Retry: kafka.RetryPolicy{
+					MaxAttempts:    cfg.Kafka.RetryMaxAttempts,
+					InitialBackoff: cfg.Kafka.RetryInitialBackoff,
+					MaxBackoff:     cfg.Kafka.RetryMaxBackoff,
+				},
+				CommitInterval: cfg.Kafka.CommitInterval,
+			},
+			orderService,
+		)
+	}
+
 	// Создаём контекст для graceful shutdown
 	ctxWithCancel, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -74,11 +144,30 @@ func main() {
 	r := mux.NewRouter()
 	orderHandler := handlers.NewOrderHandler(orderService)
 
+	// Проверки состояния
+	orderHandler.WatchConsumer("orders", consumer)
+	if statusConsumer != nil {
+		orderHandler.WatchConsumer("statuses", statusConsumer)
+	}
+	r.HandleFunc("/health", orderHandler.Health).Methods("GET")
+	r.HandleFunc("/health/live", orderHandler.Live).Methods("GET")
+	r.HandleFunc("/health/ready", orderHandler.Ready).Methods("GET")
+
 	// API для работы с заказами
 	r.HandleFunc("/order/{order_uid}", orderHandler.GetOrder).Methods("GET", "OPTIONS")
+	r.HandleFunc("/order/{order_uid}/history", orderHandler.GetOrderHistory).Methods("GET", "OPTIONS")
+	r.HandleFunc("/orders", orderHandler.ListOrders).Methods("GET", "OPTIONS")
+	r.HandleFunc("/orders/search", orderHandler.SearchOrders).Methods("GET", "OPTIONS")
+	r.HandleFunc("/orders/by-track/{track_number}", orderHandler.GetOrdersByTrackNumber).Methods("GET", "OPTIONS")
+	r.HandleFunc("/customers/{customer_id}/orders", orderHandler.GetCustomerOrders).Methods("GET", "OPTIONS")
 
 	// API для управления кешом
 	r.HandleFunc("/cache/stats", orderHandler.GetCacheStats).Methods("GET", "OPTIONS")
+	r.HandleFunc("/cache/keys", orderHandler.ListCacheKeys).Methods("GET", "OPTIONS")
+	r.HandleFunc("/cache/entries/{order_uid}", orderHandler.GetCacheEntry).Methods("GET", "OPTIONS")
+	// Регистрируется раньше /cache/invalidate/{order_uid} и без ограничения методов,
+	// иначе "bulk" примется за UID; методы, кроме POST, отклоняются с 405
+	r.HandleFunc("/cache/invalidate/bulk", orderHandler.BulkInvalidateCache)
 	r.HandleFunc("/cache/invalidate/{order_uid}", orderHandler.InvalidateCache).Methods("POST", "DELETE", "OPTIONS")
 	r.HandleFunc("/cache/invalidate", orderHandler.InvalidateCache).Methods("POST", "DELETE", "OPTIONS")
 
@@ -122,13 +211,24 @@ func main() {
 		log.Println("Останавливаем Kafka consumer...")
 		cancel()
 
-		// Ждем немного, чтобы consumer успел обработать отмену контекста
-		time.Sleep(100 * time.Millisecond)
-
-		// Создаём контекст с таймаутом для shutdown HTTP сервера
+		// Создаём контекст с таймаутом для shutdown
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer shutdownCancel()
 
+		// Ждём, пока consumer завершит обработку текущего сообщения
+		select {
+		case <-consumer.Done():
+		case <-shutdownCtx.Done():
+			log.Println("Kafka consumer не остановился вовремя")
+		}
+		if statusConsumer != nil {
+			select {
+			case <-statusConsumer.Done():
+			case <-shutdownCtx.Done():
+				log.Println("Kafka consumer статусов не остановился вовремя")
+			}
+		}
+
 		// Graceful shutdown HTTP сервера
 		log.Println("Останавливаем HTTP сервер...")
 		if err := srv.Shutdown(shutdownCtx); err != nil {
@@ -137,22 +237,73 @@ func main() {
 			log.Println("HTTP сервер успешно остановлен")
 		}
 
+		// Фиксируем offset'ы обработанных сообщений до закрытия reader'а
+		log.Println("Коммитим offset'ы Kafka...")
+		if err := consumer.Flush(shutdownCtx); err != nil {
+			log.Printf("Ошибка финального коммита offset'ов: %v", err)
+		}
+
 		// Закрываем Kafka consumer
 		log.Println("Закрываем Kafka consumer...")
 		consumer.Close()
 		log.Println("Kafka consumer успешно остановлен")
 
+		if statusConsumer != nil {
+			if err := statusConsumer.Flush(shutdownCtx); err != nil {
+				log.Printf("Ошибка финального коммита offset'ов статусов: %v", err)
+			}
+			statusConsumer.Close()
+			log.Println("Kafka consumer статусов успешно остановлен")
+		}
+
+		// Останавливаем подписку на инвалидации
+		if invalidationBus != nil {
+			select {
+			case <-invalidationBus.Done():
+			case <-shutdownCtx.Done():
+				log.Println("Подписка на инвалидации не остановилась вовремя")
+			}
+			if err := invalidationBus.Close(); err != nil {
+				log.Printf("Ошибка закрытия шины инвалидаций: %v", err)
+			}
+		}
+
+		// Сохраняем снимок кеша после остановки consumer'а, чтобы в него попали все обработанные заказы.
+		// Снимок недогретого кеша не сохраняем: догрузка после него пропустила бы старые заказы.
+		if cfg.Cache.SnapshotPath != "" && orderService.WarmupStatus().State == service.WarmupReady {
+			log.Println("Сохраняем снимок кеша...")
+			if err := orderService.SaveSnapshot(cfg.Cache.SnapshotPath); err != nil {
+				log.Printf("Ошибка сохранения снимка кеша: %v", err)
+			}
+		}
+
 		// Закрываем кеш (останавливаем горутину очистки)
 		log.Println("Останавливаем кеш...")
-		orderCache.Close()
+		cacheAdapter.Close()
 		log.Println("Кеш успешно остановлен")
 
 		log.Println("Graceful shutdown завершён")
 		shutdownComplete <- true
 	}()
 
+	// Прогреваем кэш в фоне: HTTP сервер уже отвечает, а промахи загружаются из БД.
+	// /health/ready переключается в 200 после завершения прогрева.
+	go orderService.WarmUp(ctxWithCancel, service.WarmupOptions{
+		Mode:         warmupMode,
+		RecentDays:   cfg.Warmup.RecentDays,
+		SnapshotPath: cfg.Cache.SnapshotPath,
+	})
+
+	// Подписываемся на инвалидации других реплик
+	if invalidationBus != nil {
+		go invalidationBus.Run(ctxWithCancel)
+	}
+
 	// Запускаем Kafka Consumer в горутине
 	go consumer.Start(ctxWithCancel)
+	if statusConsumer != nil {
+		go statusConsumer.Start(ctxWithCancel)
+	}
 
 	// Запускаем HTTP сервер в горутине
 	go func() {
@@ -169,3 +320,88 @@ func main() {
 	// Ожидаем завершения shutdown
 	<-shutdownComplete
 }
+
+// newCacheService создает кэш выбранной реализации с TTL и ограничениями размера из конфигурации
+func newCacheService(cfg config.CacheConfig) (service.CacheService, error) {
+	// Проверяем название стратегии вытеснения заранее
+	if _, err := cache.NewEvictionPolicy(cfg.EvictionPolicy); err != nil {
+		return nil, err
+	}
+	newEviction := func() cache.EvictionPolicy {
+		policy, _ := cache.NewEvictionPolicy(cfg.EvictionPolicy)
+		return policy
+	}
+
+	// Политика TTL по возрасту и статусу заказа (nil, если правила не заданы)
+	ageRules := make([]cache.AgeTTL, len(cfg.TTLByAge))
+	for i, rule := range cfg.TTLByAge {
+		ageRules[i] = cache.AgeTTL{MaxAge: rule.MaxAge, TTL: rule.TTL}
+	}
+	ttlPolicy := cache.NewTTLPolicy(cache.TTLPolicyOptions{
+		ByAge:         ageRules,
+		FinalStatuses: cfg.FinalStatuses,
+		FinalTTL:      cfg.FinalTTL,
+	})
+
+	switch cfg.Backend {
+	case "redis":
+		codec, err := cache.NewCodec(cfg.Redis.Codec)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Используется внешний кэш %s (префикс %q)", cfg.Redis.Addr, cfg.Redis.Prefix)
+		client := resp.NewClient(cfg.Redis.Addr, resp.Options{
+			Password:  cfg.Redis.Password,
+			DB:        cfg.Redis.DB,
+			IOTimeout: cfg.Redis.Timeout,
+		})
+		// Недоступность сервера при старте не фатальна: кэш работает как промах
+		pingCtx, cancel := context.WithTimeout(context.Background(), cfg.Redis.Timeout)
+		defer cancel()
+		if err := client.Ping(pingCtx); err != nil {
+			log.Printf("Внешний кэш %s недоступен: %v", cfg.Redis.Addr, err)
+		}
+		return service.NewRedisCacheAdapter(cache.NewRedis(client, cfg.TTL, cache.RedisOptions{
+			Prefix:      cfg.Redis.Prefix,
+			StaleGrace:  cfg.StaleGrace,
+			NegativeTTL: cfg.NegativeTTL,
+			Codec:       codec,
+			Timeout:     cfg.Redis.Timeout,
+			TTLPolicy:   ttlPolicy,
+		})), nil
+	case "sharded":
+		log.Printf("Используется шардированный кэш (%d шардов)", cfg.Shards)
+		return service.NewShardedCacheAdapter(cache.NewSharded(cfg.TTL, cache.ShardedOptions{
+			Shards:      cfg.Shards,
+			MaxEntries:  cfg.MaxEntries,
+			MaxBytes:    cfg.MaxBytes,
+			NewEviction: newEviction,
+			StaleGrace:  cfg.StaleGrace,
+			NegativeTTL: cfg.NegativeTTL,
+			TTLPolicy:   ttlPolicy,
+		})), nil
+	default:
+		return service.NewCacheAdapter(cache.NewWithOptions(cfg.TTL, cache.Options{
+			MaxEntries:  cfg.MaxEntries,
+			MaxBytes:    cfg.MaxBytes,
+			Eviction:    newEviction(),
+			StaleGrace:  cfg.StaleGrace,
+			NegativeTTL: cfg.NegativeTTL,
+			TTLPolicy:   ttlPolicy,
+		})), nil
+	}
+}
+
+// logCacheEvents пишет события кэша в лог, пока подписка не закрыта
+func logCacheEvents(sub *cache.Subscription) {
+	for ev := range sub.C {
+		if ev.Reason == cache.EventFlush {
+			log.Printf("[CACHE] %s: удалено %d записей", ev.Reason, ev.Count)
+			continue
+		}
+		log.Printf("[CACHE] %s %s", ev.Reason, ev.UID)
+	}
+	if dropped := sub.Dropped(); dropped > 0 {
+		log.Printf("[CACHE] пропущено %d событий из-за переполнения буфера", dropped)
+	}
+}
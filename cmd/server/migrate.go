@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/highdolen/L0/internal/config"
+	"github.com/highdolen/L0/internal/database"
+	"github.com/highdolen/L0/migrations"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const migrateUsage = `Использование: service migrate <команда>
+
+Команды:
+  up          применить все неприменённые миграции
+  down [N]    откатить N последних миграций (по умолчанию 1)
+  status      показать состояние миграций`
+
+// runMigrate выполняет подкоманду "migrate". Нужны только настройки БД.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("не указана команда\n%s", migrateUsage)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки конфигурации: %w", err)
+	}
+	if err := cfg.DB.Validate(); err != nil {
+		return fmt.Errorf("ошибка валидации конфигурации: %w", err)
+	}
+
+	db, err := database.ConnectDB(cfg.DB.DSN())
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к БД: %w", err)
+	}
+	defer db.Close()
+
+	runner, err := migrations.NewRunner(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := runner.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Println("Схема БД уже обновлена, новых миграций нет")
+		}
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("некорректное число миграций для отката: %q", args[1])
+			}
+		}
+		reverted, err := runner.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			log.Println("Нет применённых миграций")
+		}
+		return nil
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(statuses)
+		return nil
+	default:
+		return fmt.Errorf("неизвестная команда %q\n%s", args[0], migrateUsage)
+	}
+}
+
+func printMigrationStatus(statuses []migrations.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, st := range statuses {
+		applied := "не применена"
+		if st.AppliedAt != nil {
+			applied = st.AppliedAt.Local().Format(time.RFC3339)
+		}
+		if st.Unknown {
+			applied += " (неизвестна этой версии сервиса)"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", st.Version, st.Name, applied)
+	}
+	w.Flush()
+}
+
+// prepareSchema проверяет или обновляет схему БД при старте сервиса (DB_MIGRATE).
+// Независимо от режима сервис не запускается, если в БД нет таблиц и столбцов,
+// с которыми он работает: иначе статусы заказов ломались бы только на первом запросе.
+func prepareSchema(ctx context.Context, db *pgxpool.Pool, mode string) error {
+	if mode != "off" {
+		runner, err := migrations.NewRunner(db)
+		if err != nil {
+			return err
+		}
+		if mode == "up" {
+			_, err = runner.Up(ctx)
+		} else {
+			err = runner.Check(ctx)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return database.CheckSchema(ctx, db)
+}
@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+	"github.com/highdolen/L0/internal/resp"
+)
+
+// EntryInfo — служебные сведения о записи кэша для отладки
+type EntryInfo struct {
+	UID        string    `json:"order_uid"`
+	InsertedAt time.Time `json:"inserted_at"`
+	TTLSeconds float64   `json:"ttl_seconds"`
+	// TTLRemainingSeconds — сколько записи осталось до истечения TTL;
+	// отрицательное значение — запись устарела и отдаётся в окне StaleGrace
+	TTLRemainingSeconds float64 `json:"ttl_remaining_seconds"`
+	Stale               bool    `json:"stale"`
+	// SizeBytes — приблизительный размер записи в памяти
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// paginateKeys сортирует ключи и возвращает страницу после after.
+// next — последний ключ страницы, если за ней есть ещё ключи.
+func paginateKeys(keys []string, after string, limit int) ([]string, string) {
+	sort.Strings(keys)
+	start := sort.SearchStrings(keys, after)
+	if start < len(keys) && keys[start] == after {
+		start++
+	}
+	keys = keys[start:]
+	if limit <= 0 || len(keys) <= limit {
+		return keys, ""
+	}
+	return keys[:limit], keys[limit-1]
+}
+
+// Keys — UID заказов в кэше с заданным префиксом, по возрастанию.
+// Страница начинается после after и содержит не более limit ключей;
+// next передаётся как after для следующей страницы (пусто — страниц больше нет).
+// Окончательно устаревшие записи не возвращаются.
+func (c *OrderCache) Keys(prefix, after string, limit int) ([]string, string) {
+	return paginateKeys(c.matchingKeys(prefix, time.Now()), after, limit)
+}
+
+// matchingKeys — ключи с префиксом, не устаревшие окончательно
+func (c *OrderCache) matchingKeys(prefix string, now time.Time) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var keys []string
+	for uid, entry := range c.cache {
+		if strings.HasPrefix(uid, prefix) && c.status(entry, now) != Miss {
+			keys = append(keys, uid)
+		}
+	}
+	return keys
+}
+
+// Entry — сведения о записи заказа без учёта обращения в статистике и стратегии вытеснения
+func (c *OrderCache) Entry(uid string) (EntryInfo, bool) {
+	c.mu.RLock()
+	entry, exists := c.cache[uid]
+	c.mu.RUnlock()
+
+	now := time.Now()
+	if !exists || c.status(entry, now) == Miss {
+		return EntryInfo{}, false
+	}
+
+	ttl := c.entryTTL(entry)
+	remaining := ttl - now.Sub(entry.Timestamp)
+	return EntryInfo{
+		UID:                 uid,
+		InsertedAt:          entry.Timestamp,
+		TTLSeconds:          ttl.Seconds(),
+		TTLRemainingSeconds: remaining.Seconds(),
+		Stale:               remaining < 0,
+		SizeBytes:           entry.Size,
+	}, true
+}
+
+// DeleteWhere удаляет записи заказов, для которых match возвращает true,
+// и возвращает их UID. match вызывается под блокировкой кэша и не должен к нему обращаться.
+func (c *OrderCache) DeleteWhere(match func(order models.Order) bool) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var removed []string
+	for uid, entry := range c.cache {
+		if match(entry.Order) {
+			removed = append(removed, uid)
+		}
+	}
+	for _, uid := range removed {
+		c.remove(uid)
+		c.counters.deletes.Add(1)
+		c.events.publish(EventDelete, uid, 0)
+	}
+	return removed
+}
+
+// Keys — UID заказов всех шардов с префиксом, по возрастанию (см. OrderCache.Keys)
+func (c *ShardedCache) Keys(prefix, after string, limit int) ([]string, string) {
+	now := time.Now()
+	var keys []string
+	for _, s := range c.shards {
+		keys = append(keys, s.matchingKeys(prefix, now)...)
+	}
+	return paginateKeys(keys, after, limit)
+}
+
+// Entry — сведения о записи заказа
+func (c *ShardedCache) Entry(uid string) (EntryInfo, bool) {
+	return c.shard(uid).Entry(uid)
+}
+
+// DeleteWhere удаляет подходящие записи во всех шардах и возвращает их UID
+func (c *ShardedCache) DeleteWhere(match func(order models.Order) bool) []string {
+	var removed []string
+	for _, s := range c.shards {
+		removed = append(removed, s.DeleteWhere(match)...)
+	}
+	return removed
+}
+
+// Keys — UID заказов во внешнем кэше с префиксом, по возрастанию (см. OrderCache.Keys).
+// Сервер не умеет сортировать ключи, поэтому каждая страница требует полного обхода
+// SCAN — метод предназначен для отладки, а не для регулярных запросов.
+func (c *RedisCache) Keys(prefix, after string, limit int) ([]string, string) {
+	keyPrefix := c.orderKey("")
+	var keys []string
+	_, err := c.forEachKey(globEscape(keyPrefix+prefix)+"*", func(ctx context.Context, batch []string) error {
+		for _, k := range batch {
+			keys = append(keys, strings.TrimPrefix(k, keyPrefix))
+		}
+		return nil
+	})
+	if err != nil {
+		c.fail("keys", prefix+"*", err)
+	}
+	return paginateKeys(keys, after, limit)
+}
+
+// Entry — сведения о записи заказа во внешнем кэше
+func (c *RedisCache) Entry(uid string) (EntryInfo, bool) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	reply, err := c.client.Do(ctx, "GET", c.orderKey(uid))
+	if err != nil {
+		c.fail("entry", uid, err)
+		return EntryInfo{}, false
+	}
+	if reply.Null {
+		return EntryInfo{}, false
+	}
+	data := reply.Bytes()
+	e, err := c.opts.Codec.Decode(data)
+	if err != nil {
+		c.fail("decode", uid, err)
+		return EntryInfo{}, false
+	}
+
+	ttl := e.TTL
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+	remaining := ttl - time.Since(e.Timestamp)
+	if remaining < -c.opts.StaleGrace {
+		return EntryInfo{}, false
+	}
+	return EntryInfo{
+		UID:                 uid,
+		InsertedAt:          e.Timestamp,
+		TTLSeconds:          ttl.Seconds(),
+		TTLRemainingSeconds: remaining.Seconds(),
+		Stale:               remaining < 0,
+		SizeBytes:           int64(len(data)),
+	}, true
+}
+
+// DeleteWhere удаляет записи внешнего кэша, для которых match возвращает true,
+// и возвращает их UID. Записи читаются и декодируются пачками по ходу SCAN.
+func (c *RedisCache) DeleteWhere(match func(order models.Order) bool) []string {
+	keyPrefix := c.orderKey("")
+	var removed []string
+	_, err := c.forEachKey(globEscape(keyPrefix)+"*", func(ctx context.Context, keys []string) error {
+		reply, err := c.client.Do(ctx, append([]string{"MGET"}, keys...)...)
+		if err == nil && (reply.Kind != resp.Array || len(reply.Array) != len(keys)) {
+			err = errors.New("неожиданный ответ на MGET")
+		}
+		if err != nil {
+			return err
+		}
+
+		var matched []string
+		for i, data := range reply.Array {
+			if data.Null {
+				continue
+			}
+			e, err := c.opts.Codec.Decode(data.Bytes())
+			if err != nil {
+				c.fail("decode", keys[i], err)
+				continue
+			}
+			if match(e.Order) {
+				matched = append(matched, keys[i])
+			}
+		}
+		if len(matched) == 0 {
+			return nil
+		}
+		if _, err := c.client.Do(ctx, append([]string{"DEL"}, matched...)...); err != nil {
+			return err
+		}
+		for _, k := range matched {
+			uid := strings.TrimPrefix(k, keyPrefix)
+			removed = append(removed, uid)
+			c.counters.deletes.Add(1)
+			c.events.publish(EventDelete, uid, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		c.fail("delete-where", keyPrefix+"*", err)
+	}
+	return removed
+}
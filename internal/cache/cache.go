@@ -12,22 +12,73 @@ import (
 type CacheEntry struct {
 	Order     models.Order
 	Timestamp time.Time //момент, когда данные добавлены в кэш
+	Size      int64     // приблизительный размер записи в байтах
+	// TTL — время жизни записи; 0 — TTL кэша по умолчанию
+	TTL time.Duration
+}
+
+// LookupStatus — результат поиска записи в кэше
+type LookupStatus int
+
+const (
+	// Miss — записи нет или она устарела окончательно
+	Miss LookupStatus = iota
+	// Hit — актуальная запись
+	Hit
+	// Stale — TTL истёк, но запись ещё в пределах окна StaleGrace:
+	// её можно отдать, параллельно обновив из БД
+	Stale
+	// NegativeHit — недавно было установлено, что такого заказа нет в БД
+	NegativeHit
+)
+
+// Options — ограничения размера кэша
+type Options struct {
+	// MaxEntries — максимальное число записей (0 — без ограничения)
+	MaxEntries int
+	// MaxBytes — приблизительный максимальный объём записей в байтах (0 — без ограничения)
+	MaxBytes int64
+	// Eviction — стратегия вытеснения при превышении лимитов (по умолчанию LRU)
+	Eviction EvictionPolicy
+	// StaleGrace — сколько устаревшая запись ещё хранится и отдаётся через Lookup
+	// со статусом Stale (0 — устаревшие записи сразу считаются отсутствующими)
+	StaleGrace time.Duration
+	// NegativeTTL — сколько помнить, что заказа нет в БД (0 — негативное кэширование отключено)
+	NegativeTTL time.Duration
+	// TTLPolicy вычисляет TTL записи по заказу при Set (nil — у всех записей TTL кэша)
+	TTLPolicy TTLPolicy
 }
 
 type OrderCache struct {
 	mu       sync.RWMutex
 	cache    map[string]CacheEntry
 	ttl      time.Duration
+	grace    time.Duration
+	policy   TTLPolicy
 	stopChan chan bool
+
+	maxEntries int
+	maxBytes   int64
+	eviction   EvictionPolicy // nil, если кэш не ограничен
+	bytes      int64          // суммарный размер записей
+
+	negativeTTL time.Duration
+	negative    map[string]time.Time // UID отсутствующих заказов → момент истечения записи
+
+	indexes [indexCount]secondaryIndex // вторичные индексы по track_number и customer_id
+
+	counters counters
+	events   *eventHub
 }
 
 // New создает новый кэш с указанным TTL
 func New(ttl time.Duration) *OrderCache {
-	c := &OrderCache{
-		cache:    make(map[string]CacheEntry),
-		ttl:      ttl,
-		stopChan: make(chan bool),
-	}
+	return NewWithOptions(ttl, Options{})
+}
+
+// NewWithOptions создает кэш с указанным TTL и ограничениями размера
+func NewWithOptions(ttl time.Duration, opts Options) *OrderCache {
+	c := newOrderCache(ttl, opts)
 
 	// Запускаем горутину для очистки устаревших записей
 	go c.cleanupExpired()
@@ -35,41 +86,224 @@ func New(ttl time.Duration) *OrderCache {
 	return c
 }
 
-// Get — получить заказ по UID с проверкой TTL
+// newOrderCache создает кэш без фоновой очистки — ею управляет владелец (например, ShardedCache)
+func newOrderCache(ttl time.Duration, opts Options) *OrderCache {
+	c := &OrderCache{
+		cache:      make(map[string]CacheEntry),
+		ttl:        ttl,
+		grace:      opts.StaleGrace,
+		policy:     opts.TTLPolicy,
+		stopChan:   make(chan bool),
+		maxEntries: opts.MaxEntries,
+		maxBytes:   opts.MaxBytes,
+
+		negativeTTL: opts.NegativeTTL,
+		negative:    make(map[string]time.Time),
+		events:      newEventHub(),
+	}
+	c.resetIndexes()
+	if c.maxEntries > 0 || c.maxBytes > 0 {
+		c.eviction = opts.Eviction
+		if c.eviction == nil {
+			c.eviction = NewLRU()
+		}
+	}
+	return c
+}
+
+// Get — получить заказ по UID с проверкой TTL.
+// Устаревшие записи (в том числе в окне StaleGrace) считаются отсутствующими.
 func (c *OrderCache) Get(uid string) (models.Order, bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	order, status := c.Lookup(uid)
+	if status != Hit {
+		return models.Order{}, false
+	}
+	return order, true
+}
 
+// Lookup — найти заказ по UID и сообщить, актуальна ли запись
+func (c *OrderCache) Lookup(uid string) (models.Order, LookupStatus) {
+	order, status := c.lookup(uid)
+	c.counters.lookup(status)
+	return order, status
+}
+
+// lookup — Lookup без учёта в статистике.
+// Для неограниченного кэша чтение выполняется под разделяемой блокировкой;
+// эксклюзивная берётся только для удаления окончательно устаревшей записи.
+func (c *OrderCache) lookup(uid string) (models.Order, LookupStatus) {
+	if c.eviction != nil {
+		return c.lookupTracked(uid)
+	}
+
+	c.mu.RLock()
 	entry, exists := c.cache[uid]
+	notFoundUntil, negative := c.negative[uid]
+	c.mu.RUnlock()
 	if !exists {
-		return models.Order{}, false
+		return models.Order{}, c.negativeStatus(negative, notFoundUntil)
 	}
 
 	// Проверяем, не истек ли TTL
-	if time.Since(entry.Timestamp) > c.ttl {
+	status := c.status(entry, time.Now())
+	if status == Miss {
 		// Удаляем устаревшую запись
-		delete(c.cache, uid)
-		return models.Order{}, false
+		c.removeIfUnchanged(uid, entry.Timestamp)
+		return models.Order{}, Miss
+	}
+	return entry.Order, status
+}
+
+// lookupTracked — Lookup для ограниченного кэша: стратегии вытеснения нужно
+// учитывать каждое обращение, поэтому блокировка эксклюзивная
+func (c *OrderCache) lookupTracked(uid string) (models.Order, LookupStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.cache[uid]
+	if !exists {
+		notFoundUntil, negative := c.negative[uid]
+		return models.Order{}, c.negativeStatus(negative, notFoundUntil)
+	}
+	status := c.status(entry, time.Now())
+	if status == Miss {
+		c.remove(uid)
+		c.counters.expirations.Add(1)
+		c.events.publish(EventExpire, uid, 0)
+		return models.Order{}, Miss
+	}
+
+	c.eviction.Accessed(uid)
+	return entry.Order, status
+}
+
+// entryTTL — время жизни записи с учётом TTL по умолчанию
+func (c *OrderCache) entryTTL(entry CacheEntry) time.Duration {
+	if entry.TTL > 0 {
+		return entry.TTL
 	}
+	return c.ttl
+}
+
+// status определяет состояние записи на момент now
+func (c *OrderCache) status(entry CacheEntry, now time.Time) LookupStatus {
+	age := now.Sub(entry.Timestamp)
+	ttl := c.entryTTL(entry)
+	switch {
+	case age <= ttl:
+		return Hit
+	case age <= ttl+c.grace:
+		return Stale
+	default:
+		return Miss
+	}
+}
 
-	return entry.Order, true
+// removeIfUnchanged удаляет запись, если её не перезаписали после чтения
+func (c *OrderCache) removeIfUnchanged(uid string, timestamp time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cur, ok := c.cache[uid]; ok && cur.Timestamp.Equal(timestamp) {
+		c.remove(uid)
+		c.counters.expirations.Add(1)
+		c.events.publish(EventExpire, uid, 0)
+	}
 }
 
-// Set — добавить или обновить заказ с текущей временной меткой
+// Set — добавить или обновить заказ с текущей временной меткой.
+// TTL записи определяет политика TTL, а без неё — TTL кэша.
+// Если кэш ограничен, при переполнении вытесняются записи по выбранной стратегии.
 func (c *OrderCache) Set(uid string, order models.Order) {
+	c.SetWithTTL(uid, order, 0)
+}
+
+// SetWithTTL — добавить или обновить заказ с собственным временем жизни.
+// ttl <= 0 — TTL по политике или по умолчанию, как в Set.
+func (c *OrderCache) SetWithTTL(uid string, order models.Order, ttl time.Duration) {
+	if ttl <= 0 && c.policy != nil {
+		ttl = c.policy(order)
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.cache[uid] = CacheEntry{
+	c.set(uid, order, time.Now(), ttl)
+	c.counters.sets.Add(1)
+}
+
+// set добавляет запись с заданной временной меткой и TTL. Вызывается под c.mu.
+func (c *OrderCache) set(uid string, order models.Order, timestamp time.Time, ttl time.Duration) {
+	// Заказ появился — негативная запись больше не актуальна
+	delete(c.negative, uid)
+	c.events.publish(EventSet, uid, 0)
+
+	if old, exists := c.cache[uid]; exists {
+		c.unindexOrder(uid, old.Order, false)
+	}
+	c.indexOrder(uid, order)
+
+	entry := CacheEntry{
 		Order:     order,
-		Timestamp: time.Now(),
+		Timestamp: timestamp,
+	}
+	if ttl > 0 && ttl != c.ttl {
+		entry.TTL = ttl
+	}
+	entry.Size = estimateSize(uid, order)
+	old, exists := c.cache[uid]
+	if exists {
+		c.bytes -= old.Size
+	}
+	c.cache[uid] = entry
+	c.bytes += entry.Size
+
+	if c.eviction == nil {
+		return
+	}
+	if exists {
+		c.eviction.Accessed(uid)
+	} else {
+		c.eviction.Added(uid)
+	}
+	c.evictOverflow()
+}
+
+// evictOverflow вытесняет записи, пока кэш не уложится в лимиты. Вызывается под c.mu.
+func (c *OrderCache) evictOverflow() {
+	for (c.maxEntries > 0 && len(c.cache) > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		victim, ok := c.eviction.Victim()
+		if !ok {
+			return
+		}
+		c.remove(victim)
+		c.counters.evictions.Add(1)
+		c.events.publish(EventEvict, victim, 0)
+	}
+}
+
+// remove удаляет запись с учётом размера и стратегии вытеснения. Вызывается под c.mu.
+func (c *OrderCache) remove(uid string) {
+	entry, exists := c.cache[uid]
+	if !exists {
+		return
+	}
+	delete(c.cache, uid)
+	c.unindexOrder(uid, entry.Order, true)
+	c.bytes -= entry.Size
+	if c.eviction != nil {
+		c.eviction.Removed(uid)
 	}
 }
 
-// Delete — удалить заказ по UID
+// Delete — удалить заказ по UID (вместе с негативной записью)
 func (c *OrderCache) Delete(uid string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	delete(c.cache, uid)
+	if _, exists := c.cache[uid]; exists {
+		c.remove(uid)
+		c.counters.deletes.Add(1)
+		c.events.publish(EventDelete, uid, 0)
+	}
+	delete(c.negative, uid)
 }
 
 // Invalidate — инвалидировать конкретный заказ
@@ -79,33 +313,61 @@ func (c *OrderCache) Invalidate(uid string) {
 
 // InvalidateAll — очистить весь кэш
 func (c *OrderCache) InvalidateAll() {
+	removed := c.clear()
+	c.events.publish(EventFlush, "", removed)
+	log.Println("Кэш полностью очищен")
+}
+
+// clear удаляет все записи и возвращает их число
+func (c *OrderCache) clear() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.eviction != nil {
+		for uid := range c.cache {
+			c.eviction.Removed(uid)
+		}
+	}
+	c.bytes = 0
+	removed := len(c.cache)
+	c.counters.deletes.Add(uint64(removed))
 	c.cache = make(map[string]CacheEntry)
-	log.Println("Кэш полностью очищен")
+	c.negative = make(map[string]time.Time)
+	c.resetIndexes()
+	return removed
 }
 
-// GetStats — получить статистику кэша
-func (c *OrderCache) GetStats() map[string]interface{} {
+// collectStats подсчитывает записи, счётчики и лимиты кэша на момент now
+func (c *OrderCache) collectStats(now time.Time) CacheStats {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	totalEntries := len(c.cache)
-	expiredEntries := 0
-	now := time.Now()
-
+	st := CacheStats{
+		TTLSeconds:      c.ttl.Seconds(),
+		Entries:         len(c.cache),
+		NegativeEntries: len(c.negative),
+		ApproxBytes:     c.bytes,
+	}
+	c.counters.fill(&st)
 	for _, entry := range c.cache {
-		if now.Sub(entry.Timestamp) > c.ttl {
-			expiredEntries++
+		if now.Sub(entry.Timestamp) > c.entryTTL(entry) {
+			st.ExpiredEntries++
 		}
 	}
-
-	return map[string]interface{}{
-		"total_entries":   totalEntries,
-		"expired_entries": expiredEntries,
-		"valid_entries":   totalEntries - expiredEntries,
-		"ttl_minutes":     c.ttl.Minutes(),
+	if c.eviction != nil {
+		st.EvictionPolicy = c.eviction.Name()
+		st.MaxEntries = c.maxEntries
+		st.MaxBytes = c.maxBytes
 	}
+	return st
+}
+
+// GetStats — получить статистику кэша
+func (c *OrderCache) GetStats() CacheStats {
+	st := c.collectStats(time.Now())
+	st.Backend = "memory"
+	st.Shards = 1
+	st.finish()
+	return st
 }
 
 // cleanupExpired — горутина для очистки устаревших записей
@@ -116,31 +378,65 @@ func (c *OrderCache) cleanupExpired() {
 	for {
 		select {
 		case <-ticker.C:
-			c.mu.Lock()
-			now := time.Now()
-			expiredKeys := make([]string, 0)
-
-			for key, entry := range c.cache {
-				if now.Sub(entry.Timestamp) >= c.ttl {
-					expiredKeys = append(expiredKeys, key)
-				}
-			}
-
-			for _, key := range expiredKeys {
-				delete(c.cache, key)
+			if removed := c.sweep(time.Now()); removed > 0 {
+				log.Printf("Удалено %d устаревших записей из кэша", removed)
 			}
 
-			if len(expiredKeys) > 0 {
-				log.Printf("Удалено %d устаревших записей из кэша", len(expiredKeys))
-			}
-			c.mu.Unlock()
-
 		case <-c.stopChan:
 			return
 		}
 	}
 }
 
+// sweepChunk — сколько записей удаляется за одно взятие эксклюзивной блокировки
+const sweepChunk = 1000
+
+// sweep удаляет устаревшие записи и возвращает их количество.
+// Поиск идёт под разделяемой блокировкой, а удаление — небольшими порциями,
+// чтобы не блокировать чтение на время обхода всего кэша.
+func (c *OrderCache) sweep(now time.Time) int {
+	c.mu.RLock()
+	expiredKeys := make([]string, 0)
+	for key, entry := range c.cache {
+		if c.status(entry, now) == Miss {
+			expiredKeys = append(expiredKeys, key)
+		}
+	}
+	c.mu.RUnlock()
+
+	removed := 0
+	for start := 0; start < len(expiredKeys); start += sweepChunk {
+		end := start + sweepChunk
+		if end > len(expiredKeys) {
+			end = len(expiredKeys)
+		}
+
+		c.mu.Lock()
+		for _, key := range expiredKeys[start:end] {
+			// Запись могла обновиться, пока блокировка была отпущена
+			if entry, ok := c.cache[key]; ok && c.status(entry, now) == Miss {
+				c.remove(key)
+				c.counters.expirations.Add(1)
+				c.events.publish(EventExpire, key, 0)
+				removed++
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	c.sweepNegative(now)
+	c.sweepIndexes(now)
+	return removed
+}
+
+// Subscribe — подписаться на события кэша (set, delete, expire, evict, flush).
+// buffer — размер буфера канала (0 — по умолчанию); при переполнении события
+// отбрасываются, операции с кэшем никогда не ждут подписчика.
+// Подписку нужно закрыть через Close.
+func (c *OrderCache) Subscribe(buffer int) *Subscription {
+	return c.events.subscribe(buffer)
+}
+
 // Close — остановить горутину очистки
 func (c *OrderCache) Close() {
 	close(c.stopChan)
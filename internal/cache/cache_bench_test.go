@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// Сравнение OrderCache (один мьютекс) и ShardedCache под параллельной нагрузкой:
+//
+//	go test -bench=. -benchmem -cpu=1,4,8 ./internal/cache/
+
+const benchKeys = 10000
+
+// benchCache — общие методы сравниваемых реализаций
+type benchCache interface {
+	Get(uid string) (models.Order, bool)
+	Set(uid string, order models.Order)
+	Close()
+}
+
+func benchOrder(uid string) models.Order {
+	return models.Order{
+		OrderUID:    uid,
+		TrackNumber: "TRACK" + uid,
+		CustomerID:  "customer",
+		DateCreated: time.Now(),
+		Items:       []models.Item{{ChrtID: 1, Name: "item", Brand: "brand"}},
+	}
+}
+
+func benchUIDs() []string {
+	uids := make([]string, benchKeys)
+	for i := range uids {
+		uids[i] = "order_" + strconv.Itoa(i)
+	}
+	return uids
+}
+
+func fill(c benchCache, uids []string) {
+	for _, uid := range uids {
+		c.Set(uid, benchOrder(uid))
+	}
+}
+
+// runMixed выполняет параллельную нагрузку, где writePercent процентов операций — запись
+func runMixed(b *testing.B, c benchCache, writePercent int) {
+	uids := benchUIDs()
+	fill(c, uids)
+	defer c.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			uid := uids[i%len(uids)]
+			if i%100 < writePercent {
+				c.Set(uid, benchOrder(uid))
+			} else {
+				c.Get(uid)
+			}
+			i += 7 // разносим горутины по разным ключам
+		}
+	})
+}
+
+func BenchmarkOrderCacheGet(b *testing.B) {
+	runMixed(b, New(time.Hour), 0)
+}
+
+func BenchmarkShardedCacheGet(b *testing.B) {
+	runMixed(b, NewSharded(time.Hour, ShardedOptions{}), 0)
+}
+
+func BenchmarkOrderCacheMixed(b *testing.B) {
+	runMixed(b, New(time.Hour), 10)
+}
+
+func BenchmarkShardedCacheMixed(b *testing.B) {
+	runMixed(b, NewSharded(time.Hour, ShardedOptions{}), 10)
+}
+
+func BenchmarkOrderCacheGetLRU(b *testing.B) {
+	runMixed(b, NewWithOptions(time.Hour, Options{MaxEntries: benchKeys * 2}), 0)
+}
+
+func BenchmarkShardedCacheGetLRU(b *testing.B) {
+	runMixed(b, NewSharded(time.Hour, ShardedOptions{MaxEntries: benchKeys * 2, NewEviction: NewLRU}), 0)
+}
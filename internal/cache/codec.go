@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// Codec сериализует записи кэша для внешнего хранилища
+type Codec interface {
+	Name() string
+	Encode(e CodecEntry) ([]byte, error)
+	Decode(data []byte) (CodecEntry, error)
+}
+
+// NewCodec возвращает кодек по названию: json или gob
+func NewCodec(name string) (Codec, error) {
+	switch name {
+	case "", "json":
+		return JSONCodec{}, nil
+	case "gob":
+		return GobCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown cache codec %q", name)
+	}
+}
+
+// CodecEntry — запись во внешнем кэше: заказ, момент кэширования и собственный TTL
+type CodecEntry struct {
+	Order     models.Order  `json:"order"`
+	Timestamp time.Time     `json:"ts"`
+	TTL       time.Duration `json:"ttl,omitempty"`
+}
+
+// JSONCodec хранит записи в JSON — их удобно смотреть redis-cli,
+// но внутренние ID delivery и payment не сохраняются
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string { return "json" }
+
+func (JSONCodec) Encode(e CodecEntry) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (JSONCodec) Decode(data []byte) (CodecEntry, error) {
+	var e CodecEntry
+	err := json.Unmarshal(data, &e)
+	return e, err
+}
+
+// GobCodec хранит записи в gob — компактнее и быстрее JSON
+type GobCodec struct{}
+
+func (GobCodec) Name() string { return "gob" }
+
+func (GobCodec) Encode(e CodecEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Decode(data []byte) (CodecEntry, error) {
+	var e CodecEntry
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&e)
+	return e, err
+}
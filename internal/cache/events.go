@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventReason — что произошло с записью кэша
+type EventReason string
+
+const (
+	// EventSet — запись добавлена или обновлена
+	EventSet EventReason = "set"
+	// EventDelete — запись удалена явно (Delete, инвалидация)
+	EventDelete EventReason = "delete"
+	// EventExpire — запись удалена по истечении TTL
+	EventExpire EventReason = "expire"
+	// EventEvict — запись вытеснена из-за ограничения размера
+	EventEvict EventReason = "evict"
+	// EventFlush — кэш очищен полностью; UID пустой, Count — число удалённых записей
+	EventFlush EventReason = "flush"
+)
+
+// Event — событие изменения содержимого кэша
+type Event struct {
+	Reason EventReason `json:"reason"`
+	UID    string      `json:"order_uid,omitempty"`
+	Count  int         `json:"count,omitempty"`
+	At     time.Time   `json:"at"`
+}
+
+// defaultEventBuffer — размер буфера подписки по умолчанию
+const defaultEventBuffer = 256
+
+// Subscription — подписка на события кэша. События читаются из C;
+// если подписчик не успевает, новые события отбрасываются, а не задерживают
+// операции с кэшем.
+type Subscription struct {
+	C <-chan Event
+
+	ch      chan Event
+	hub     *eventHub
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// Dropped — сколько событий отброшено из-за переполнения буфера
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close отменяет подписку и закрывает канал C
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.unsubscribe(s)
+		close(s.ch)
+	})
+}
+
+// eventHub рассылает события подписчикам. Пока подписчиков нет,
+// публикация сводится к одной атомарной проверке.
+type eventHub struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	active atomic.Int32
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[*Subscription]struct{})}
+}
+
+func (h *eventHub) subscribe(buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = defaultEventBuffer
+	}
+	ch := make(chan Event, buffer)
+	s := &Subscription{C: ch, ch: ch, hub: h}
+
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.active.Store(int32(len(h.subs)))
+	h.mu.Unlock()
+	return s
+}
+
+func (h *eventHub) unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	delete(h.subs, s)
+	h.active.Store(int32(len(h.subs)))
+	h.mu.Unlock()
+}
+
+// publish отправляет событие всем подписчикам без ожидания.
+// Может вызываться под блокировкой кэша.
+func (h *eventHub) publish(reason EventReason, uid string, count int) {
+	if h.active.Load() == 0 {
+		return
+	}
+	ev := Event{Reason: reason, UID: uid, Count: count, At: time.Now()}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subs {
+		select {
+		case s.ch <- ev:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+)
+
+// EvictionPolicy — стратегия выбора записи для вытеснения при переполнении кэша.
+// Методы вызываются под мьютексом кэша, поэтому реализации не обязаны быть потокобезопасными.
+type EvictionPolicy interface {
+	// Name возвращает название стратегии для статистики
+	Name() string
+	// Added вызывается при добавлении новой записи
+	Added(key string)
+	// Accessed вызывается при чтении или обновлении существующей записи
+	Accessed(key string)
+	// Removed вызывается при удалении записи по любой причине
+	Removed(key string)
+	// Victim возвращает запись, которую следует вытеснить первой
+	Victim() (string, bool)
+}
+
+// NewEvictionPolicy создает стратегию вытеснения по названию: "lru" или "lfu"
+func NewEvictionPolicy(name string) (EvictionPolicy, error) {
+	switch strings.ToLower(name) {
+	case "", "lru":
+		return NewLRU(), nil
+	case "lfu":
+		return NewLFU(), nil
+	default:
+		return nil, fmt.Errorf("unknown eviction policy %q", name)
+	}
+}
+
+// lruPolicy вытесняет запись, к которой дольше всего не обращались
+type lruPolicy struct {
+	order *list.List // от недавно использованных к давно использованным
+	items map[string]*list.Element
+}
+
+// NewLRU создает стратегию Least Recently Used
+func NewLRU() EvictionPolicy {
+	return &lruPolicy{
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (p *lruPolicy) Name() string { return "lru" }
+
+func (p *lruPolicy) Added(key string) {
+	if el, ok := p.items[key]; ok {
+		p.order.MoveToFront(el)
+		return
+	}
+	p.items[key] = p.order.PushFront(key)
+}
+
+func (p *lruPolicy) Accessed(key string) {
+	if el, ok := p.items[key]; ok {
+		p.order.MoveToFront(el)
+	}
+}
+
+func (p *lruPolicy) Removed(key string) {
+	if el, ok := p.items[key]; ok {
+		p.order.Remove(el)
+		delete(p.items, key)
+	}
+}
+
+func (p *lruPolicy) Victim() (string, bool) {
+	el := p.order.Back()
+	if el == nil {
+		return "", false
+	}
+	return el.Value.(string), true
+}
+
+// lfuPolicy вытесняет запись с наименьшим числом обращений,
+// при равенстве — ту, к которой дольше всего не обращались.
+// Все операции выполняются за O(1) за счёт списков по частотам.
+type lfuPolicy struct {
+	items   map[string]*list.Element // элемент списка buckets[freq]
+	freqs   map[string]int
+	buckets map[int]*list.List
+	minFreq int
+}
+
+// NewLFU создает стратегию Least Frequently Used
+func NewLFU() EvictionPolicy {
+	return &lfuPolicy{
+		items:   make(map[string]*list.Element),
+		freqs:   make(map[string]int),
+		buckets: make(map[int]*list.List),
+	}
+}
+
+func (p *lfuPolicy) Name() string { return "lfu" }
+
+func (p *lfuPolicy) Added(key string) {
+	if _, ok := p.items[key]; ok {
+		p.Accessed(key)
+		return
+	}
+	p.push(key, 1)
+	p.minFreq = 1
+}
+
+func (p *lfuPolicy) Accessed(key string) {
+	el, ok := p.items[key]
+	if !ok {
+		return
+	}
+	freq := p.freqs[key]
+	p.unlink(el, freq)
+	if freq == p.minFreq && p.buckets[freq] == nil {
+		p.minFreq = freq + 1
+	}
+	p.push(key, freq+1)
+}
+
+func (p *lfuPolicy) Removed(key string) {
+	el, ok := p.items[key]
+	if !ok {
+		return
+	}
+	p.unlink(el, p.freqs[key])
+	delete(p.items, key)
+	delete(p.freqs, key)
+}
+
+func (p *lfuPolicy) Victim() (string, bool) {
+	if len(p.items) == 0 {
+		return "", false
+	}
+	bucket := p.buckets[p.minFreq]
+	if bucket == nil {
+		// minFreq мог устареть после удалений — находим минимальную частоту заново
+		p.minFreq = 0
+		for freq := range p.buckets {
+			if p.minFreq == 0 || freq < p.minFreq {
+				p.minFreq = freq
+			}
+		}
+		bucket = p.buckets[p.minFreq]
+	}
+	return bucket.Back().Value.(string), true
+}
+
+func (p *lfuPolicy) push(key string, freq int) {
+	bucket := p.buckets[freq]
+	if bucket == nil {
+		bucket = list.New()
+		p.buckets[freq] = bucket
+	}
+	p.items[key] = bucket.PushFront(key)
+	p.freqs[key] = freq
+}
+
+func (p *lfuPolicy) unlink(el *list.Element, freq int) {
+	bucket := p.buckets[freq]
+	bucket.Remove(el)
+	if bucket.Len() == 0 {
+		delete(p.buckets, freq)
+	}
+}
@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// op — операция над стратегией вытеснения: "+key" — Added, "key" — Accessed, "-key" — Removed
+func applyOps(p EvictionPolicy, ops []string) {
+	for _, op := range ops {
+		switch {
+		case strings.HasPrefix(op, "+"):
+			p.Added(op[1:])
+		case strings.HasPrefix(op, "-"):
+			p.Removed(op[1:])
+		default:
+			p.Accessed(op)
+		}
+	}
+}
+
+// victims вытесняет записи по одной и возвращает порядок вытеснения
+func victims(p EvictionPolicy) []string {
+	var order []string
+	for {
+		key, ok := p.Victim()
+		if !ok {
+			return order
+		}
+		order = append(order, key)
+		p.Removed(key)
+	}
+}
+
+func TestEvictionOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy func() EvictionPolicy
+		ops    []string
+		want   []string
+	}{
+		{"lru insertion order", NewLRU, []string{"+a", "+b", "+c"}, []string{"a", "b", "c"}},
+		{"lru access refreshes", NewLRU, []string{"+a", "+b", "+c", "a"}, []string{"b", "c", "a"}},
+		{"lru re-add refreshes", NewLRU, []string{"+a", "+b", "+a"}, []string{"b", "a"}},
+		{"lru removed is skipped", NewLRU, []string{"+a", "+b", "+c", "-a"}, []string{"b", "c"}},
+		{"lru access unknown key", NewLRU, []string{"+a", "x"}, []string{"a"}},
+		{"lfu least frequent first", NewLFU, []string{"+a", "+b", "+c", "a", "a", "b"}, []string{"c", "b", "a"}},
+		{"lfu ties by recency", NewLFU, []string{"+a", "+b", "+c", "a", "b", "c"}, []string{"a", "b", "c"}},
+		{"lfu new entry is least frequent", NewLFU, []string{"+a", "a", "a", "+b"}, []string{"b", "a"}},
+		{"lfu re-add counts as access", NewLFU, []string{"+a", "+b", "+a"}, []string{"b", "a"}},
+		// После удаления единственной записи с минимальной частотой minFreq устаревает
+		{"lfu stale min frequency", NewLFU, []string{"+a", "+b", "b", "b", "a", "-a"}, []string{"b"}},
+		{"lfu removed is skipped", NewLFU, []string{"+a", "+b", "b", "-a", "+c"}, []string{"c", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.policy()
+			applyOps(p, tt.ops)
+			if got := victims(p); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("порядок вытеснения %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEvictionPolicy(t *testing.T) {
+	for name, want := range map[string]string{"": "lru", "lru": "lru", "LFU": "lfu"} {
+		p, err := NewEvictionPolicy(name)
+		if err != nil || p.Name() != want {
+			t.Fatalf("NewEvictionPolicy(%q) = %v, %v, want %s", name, p, err, want)
+		}
+	}
+	if _, err := NewEvictionPolicy("fifo"); err == nil {
+		t.Fatal("NewEvictionPolicy(fifo): ожидалась ошибка")
+	}
+}
+
+func TestCacheEvictsByEntries(t *testing.T) {
+	c := NewWithOptions(time.Minute, Options{MaxEntries: 2, Eviction: NewLRU()})
+	defer c.Close()
+
+	c.Set("a", benchOrder("a"))
+	c.Set("b", benchOrder("b"))
+	c.Get("a")
+	c.Set("c", benchOrder("c"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b должен быть вытеснен как давно не использованный")
+	}
+	for _, uid := range []string{"a", "c"} {
+		if _, ok := c.Get(uid); !ok {
+			t.Fatalf("%s не должен быть вытеснен", uid)
+		}
+	}
+	if st := c.GetStats(); st.Evictions != 1 || st.Entries != 2 {
+		t.Fatalf("evictions = %d, entries = %d, want 1 и 2", st.Evictions, st.Entries)
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	small := benchOrder("a")
+	big := benchOrder("a")
+	big.Delivery.Address = strings.Repeat("x", 1000)
+	if d := estimateSize("a", big) - estimateSize("a", small); d != 1000 {
+		t.Fatalf("строка в 1000 байт увеличила оценку на %d", d)
+	}
+
+	more := benchOrder("a")
+	more.Items = append(more.Items, models.Item{Name: "second"})
+	if estimateSize("a", more) <= estimateSize("a", small) {
+		t.Fatal("дополнительный товар должен увеличивать оценку")
+	}
+}
+
+func TestCacheBytesAccounting(t *testing.T) {
+	order := benchOrder("a")
+	size := estimateSize("order_0", order)
+
+	// Лимит вмещает ровно три записи одинакового размера
+	c := NewWithOptions(time.Minute, Options{MaxBytes: 3 * size, Eviction: NewLRU()})
+	defer c.Close()
+
+	for i := 0; i < 5; i++ {
+		uid := "order_" + strconv.Itoa(i)
+		c.Set(uid, order)
+	}
+	st := c.GetStats()
+	if st.Entries != 3 || st.ApproxBytes != 3*size || st.Evictions != 2 {
+		t.Fatalf("entries = %d, bytes = %d, evictions = %d, want 3, %d, 2", st.Entries, st.ApproxBytes, st.Evictions, 3*size)
+	}
+
+	// Перезапись записи не удваивает учтённый объём
+	c.Set("order_4", order)
+	if st := c.GetStats(); st.ApproxBytes != 3*size {
+		t.Fatalf("после перезаписи bytes = %d, want %d", st.ApproxBytes, 3*size)
+	}
+
+	c.Delete("order_4")
+	if st := c.GetStats(); st.ApproxBytes != 2*size {
+		t.Fatalf("после удаления bytes = %d, want %d", st.ApproxBytes, 2*size)
+	}
+
+	c.InvalidateAll()
+	if st := c.GetStats(); st.ApproxBytes != 0 || st.Entries != 0 {
+		t.Fatalf("после очистки bytes = %d, entries = %d, want 0", st.ApproxBytes, st.Entries)
+	}
+}
+
+func TestUnboundedCacheBytesAccounting(t *testing.T) {
+	order := benchOrder("a")
+	size := estimateSize("order_0", order)
+
+	caches := []struct {
+		name  string
+		cache interface {
+			Set(uid string, order models.Order)
+			Delete(uid string)
+			InvalidateAll()
+			GetStats() CacheStats
+			Close()
+		}
+	}{
+		{"OrderCache", New(time.Minute)},
+		{"ShardedCache", NewSharded(time.Minute, ShardedOptions{Shards: 4})},
+	}
+
+	for _, tc := range caches {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.cache
+			defer c.Close()
+
+			for i := 0; i < 3; i++ {
+				c.Set("order_"+strconv.Itoa(i), order)
+			}
+			// Без стратегии вытеснения объём всё равно учитывается
+			st := c.GetStats()
+			if st.EvictionPolicy != "" || st.ApproxBytes != 3*size {
+				t.Fatalf("policy = %q, bytes = %d, want \"\", %d", st.EvictionPolicy, st.ApproxBytes, 3*size)
+			}
+
+			c.Set("order_2", order)
+			if st := c.GetStats(); st.ApproxBytes != 3*size {
+				t.Fatalf("после перезаписи bytes = %d, want %d", st.ApproxBytes, 3*size)
+			}
+
+			c.Delete("order_2")
+			if st := c.GetStats(); st.ApproxBytes != 2*size {
+				t.Fatalf("после удаления bytes = %d, want %d", st.ApproxBytes, 2*size)
+			}
+
+			c.InvalidateAll()
+			if st := c.GetStats(); st.ApproxBytes != 0 {
+				t.Fatalf("после очистки bytes = %d, want 0", st.ApproxBytes)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// Index — вторичный индекс кэша: поиск заказов по полю, отличному от UID
+type Index int
+
+const (
+	// ByTrackNumber — заказы с заданным track_number
+	ByTrackNumber Index = iota
+	// ByCustomer — заказы покупателя с заданным customer_id
+	ByCustomer
+
+	indexCount
+)
+
+// String — название индекса для логов
+func (i Index) String() string {
+	switch i {
+	case ByTrackNumber:
+		return "track_number"
+	case ByCustomer:
+		return "customer_id"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseIndex — индекс по названию (см. String)
+func ParseIndex(name string) (Index, bool) {
+	for i := Index(0); i < indexCount; i++ {
+		if i.String() == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Value — значение индексируемого поля заказа
+func (i Index) Value(order models.Order) string {
+	switch i {
+	case ByTrackNumber:
+		return order.TrackNumber
+	case ByCustomer:
+		return order.CustomerID
+	default:
+		return ""
+	}
+}
+
+// secondaryIndex хранит UID закэшированных заказов по значению поля.
+//
+// Само по себе наличие заказов в индексе не означает, что в кэше есть все
+// заказы с этим значением. Поэтому для значений, загруженных из БД целиком
+// (SetIndexed), хранится отметка полноты. Set нового заказа дополняет набор и
+// полноту не нарушает, а удаление, вытеснение или истечение любого заказа из
+// набора отметку снимает — следующий поиск пойдёт в БД.
+type secondaryIndex struct {
+	uids map[string]map[string]struct{}
+	// complete — значение поля → момент истечения отметки полноты
+	complete map[string]time.Time
+}
+
+func newSecondaryIndex() secondaryIndex {
+	return secondaryIndex{
+		uids:     make(map[string]map[string]struct{}),
+		complete: make(map[string]time.Time),
+	}
+}
+
+func (ix *secondaryIndex) add(value, uid string) {
+	set, ok := ix.uids[value]
+	if !ok {
+		set = make(map[string]struct{})
+		ix.uids[value] = set
+	}
+	set[uid] = struct{}{}
+}
+
+func (ix *secondaryIndex) remove(value, uid string, incomplete bool) {
+	if set, ok := ix.uids[value]; ok {
+		delete(set, uid)
+		if len(set) == 0 {
+			delete(ix.uids, value)
+		}
+	}
+	if incomplete {
+		delete(ix.complete, value)
+	}
+}
+
+// indexOrder добавляет заказ во вторичные индексы. Вызывается под c.mu.
+func (c *OrderCache) indexOrder(uid string, order models.Order) {
+	for i := range c.indexes {
+		if v := Index(i).Value(order); v != "" {
+			c.indexes[i].add(v, uid)
+		}
+	}
+}
+
+// unindexOrder убирает заказ из вторичных индексов. Если заказ пропадает из
+// кэша (incomplete), наборы, где он был, перестают быть полными. Вызывается под c.mu.
+func (c *OrderCache) unindexOrder(uid string, order models.Order, incomplete bool) {
+	for i := range c.indexes {
+		if v := Index(i).Value(order); v != "" {
+			c.indexes[i].remove(v, uid, incomplete)
+		}
+	}
+}
+
+// resetIndexes очищает вторичные индексы. Вызывается под c.mu.
+func (c *OrderCache) resetIndexes() {
+	for i := range c.indexes {
+		c.indexes[i] = newSecondaryIndex()
+	}
+}
+
+// LookupIndex — все заказы со значением value по индексу idx.
+// Возвращает false, если набор не был загружен целиком через SetIndexed,
+// отметка полноты истекла (TTL кэша) или какая-либо запись устарела —
+// тогда заказы нужно загрузить из БД. Пустой список с true означает,
+// что таких заказов нет.
+func (c *OrderCache) LookupIndex(idx Index, value string) ([]models.Order, bool) {
+	now := time.Now()
+	if c.eviction == nil {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.lookupIndex(idx, value, now, false)
+	}
+	// Для ограниченного кэша обращения учитываются стратегией вытеснения
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lookupIndex(idx, value, now, true)
+}
+
+func (c *OrderCache) lookupIndex(idx Index, value string, now time.Time, track bool) ([]models.Order, bool) {
+	ix := &c.indexes[idx]
+	until, ok := ix.complete[value]
+	if !ok || now.After(until) {
+		return nil, false
+	}
+
+	set := ix.uids[value]
+	orders := make([]models.Order, 0, len(set))
+	for uid := range set {
+		entry, exists := c.cache[uid]
+		if !exists || c.status(entry, now) != Hit {
+			return nil, false
+		}
+		orders = append(orders, entry.Order)
+	}
+	if track {
+		for uid := range set {
+			c.eviction.Accessed(uid)
+		}
+	}
+	return orders, true
+}
+
+// SetIndexed кэширует полный набор заказов со значением value по индексу idx,
+// загруженный из БД, и отмечает его полным на время TTL кэша.
+// Пустой набор запоминается как отсутствие таких заказов.
+func (c *OrderCache) SetIndexed(idx Index, value string, orders []models.Order) {
+	ttls := make([]time.Duration, len(orders))
+	if c.policy != nil {
+		for i, order := range orders {
+			ttls[i] = c.policy(order)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for i, order := range orders {
+		c.set(order.OrderUID, order, now, ttls[i])
+		c.counters.sets.Add(1)
+	}
+	// Вытеснение могло убрать часть только что добавленных заказов
+	for _, order := range orders {
+		if _, ok := c.cache[order.OrderUID]; !ok {
+			return
+		}
+	}
+	ix := &c.indexes[idx]
+	// Для ограниченного кэша не даём отметкам расти сверх лимита записей
+	if c.maxEntries > 0 && len(ix.complete) >= c.maxEntries {
+		return
+	}
+	ix.complete[value] = now.Add(c.ttl)
+}
+
+// DeleteIndexed удаляет из кэша все заказы со значением value по индексу idx
+// и возвращает их UID. Заказы находятся по индексу, без обхода всего кэша.
+func (c *OrderCache) DeleteIndexed(idx Index, value string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	set := c.indexes[idx].uids[value]
+	removed := make([]string, 0, len(set))
+	for uid := range set {
+		removed = append(removed, uid)
+	}
+	for _, uid := range removed {
+		c.remove(uid)
+		c.counters.deletes.Add(1)
+		c.events.publish(EventDelete, uid, 0)
+	}
+	delete(c.indexes[idx].complete, value)
+	return removed
+}
+
+// sweepIndexes удаляет истёкшие отметки полноты
+func (c *OrderCache) sweepIndexes(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.indexes {
+		for value, until := range c.indexes[i].complete {
+			if now.After(until) {
+				delete(c.indexes[i].complete, value)
+			}
+		}
+	}
+}
+
+// LookupIndex — заказы по вторичному индексу во всех шардах (см. OrderCache.LookupIndex).
+// Набор полон, только если он полон в каждом шарде.
+func (c *ShardedCache) LookupIndex(idx Index, value string) ([]models.Order, bool) {
+	var orders []models.Order
+	for _, s := range c.shards {
+		part, ok := s.LookupIndex(idx, value)
+		if !ok {
+			return nil, false
+		}
+		orders = append(orders, part...)
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	return orders, true
+}
+
+// SetIndexed распределяет заказы по шардам и отмечает набор полным в каждом шарде,
+// в том числе в тех, куда не попало ни одного заказа
+func (c *ShardedCache) SetIndexed(idx Index, value string, orders []models.Order) {
+	parts := make([][]models.Order, len(c.shards))
+	for _, order := range orders {
+		i := c.shardIndex(order.OrderUID)
+		parts[i] = append(parts[i], order)
+	}
+	for i, s := range c.shards {
+		s.SetIndexed(idx, value, parts[i])
+	}
+}
+
+// DeleteIndexed удаляет заказы со значением value по индексу idx во всех шардах
+func (c *ShardedCache) DeleteIndexed(idx Index, value string) []string {
+	var removed []string
+	for _, s := range c.shards {
+		removed = append(removed, s.DeleteIndexed(idx, value)...)
+	}
+	return removed
+}
+
+// LookupIndex не поддерживается внешним кэшем: заказы всегда загружаются из БД
+func (c *RedisCache) LookupIndex(idx Index, value string) ([]models.Order, bool) {
+	return nil, false
+}
+
+// SetIndexed кэширует заказы по UID; вторичные индексы внешним кэшем не хранятся
+func (c *RedisCache) SetIndexed(idx Index, value string, orders []models.Order) {
+	for _, order := range orders {
+		c.Set(order.OrderUID, order)
+	}
+}
+
+// DeleteIndexed удаляет заказы со значением value по индексу idx. Вторичных
+// индексов во внешнем кэше нет, поэтому записи находятся обходом SCAN, как в DeleteWhere.
+func (c *RedisCache) DeleteIndexed(idx Index, value string) []string {
+	return c.DeleteWhere(func(order models.Order) bool {
+		return idx.Value(order) == value
+	})
+}
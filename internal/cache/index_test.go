@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+	"github.com/highdolen/L0/internal/resp"
+	"github.com/highdolen/L0/internal/resp/resptest"
+)
+
+// indexedCache — методы кэшей, участвующие в удалении по индексу
+type indexedCache interface {
+	Set(uid string, order models.Order)
+	Get(uid string) (models.Order, bool)
+	SetIndexed(idx Index, value string, orders []models.Order)
+	LookupIndex(idx Index, value string) ([]models.Order, bool)
+	DeleteIndexed(idx Index, value string) []string
+	Close()
+}
+
+func customerOrder(uid, customer string) models.Order {
+	order := benchOrder(uid)
+	order.CustomerID = customer
+	return order
+}
+
+func TestDeleteIndexed(t *testing.T) {
+	srv, err := resptest.NewServer()
+	if err != nil {
+		t.Fatalf("запуск RESP-сервера: %v", err)
+	}
+	defer srv.Close()
+
+	caches := []struct {
+		name  string
+		cache indexedCache
+	}{
+		{"OrderCache", New(time.Hour)},
+		{"ShardedCache", NewSharded(time.Hour, ShardedOptions{Shards: 4})},
+		{"RedisCache", NewRedis(resp.NewClient(srv.Addr(), resp.Options{}), time.Hour, RedisOptions{})},
+	}
+
+	for _, tc := range caches {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.cache
+			defer c.Close()
+
+			c.SetIndexed(ByCustomer, "alice", []models.Order{customerOrder("a1", "alice"), customerOrder("a2", "alice")})
+			c.Set("a3", customerOrder("a3", "alice"))
+			c.Set("b1", customerOrder("b1", "bob"))
+
+			removed := c.DeleteIndexed(ByCustomer, "alice")
+			slices.Sort(removed)
+			if want := []string{"a1", "a2", "a3"}; !slices.Equal(removed, want) {
+				t.Fatalf("DeleteIndexed удалил %v, ожидалось %v", removed, want)
+			}
+			for _, uid := range removed {
+				if _, ok := c.Get(uid); ok {
+					t.Errorf("заказ %s остался в кэше", uid)
+				}
+			}
+			if _, ok := c.Get("b1"); !ok {
+				t.Errorf("удалён заказ другого покупателя")
+			}
+			// Набор больше не считается полным — следующий поиск пойдёт в БД
+			if _, ok := c.LookupIndex(ByCustomer, "alice"); ok {
+				t.Errorf("набор заказов покупателя остался полным после удаления")
+			}
+			if removed := c.DeleteIndexed(ByCustomer, "alice"); len(removed) != 0 {
+				t.Errorf("повторное удаление вернуло %v", removed)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package cache
+
+import "time"
+
+// SetNotFound — запомнить, что заказа нет в БД, на время NegativeTTL.
+// Повторные запросы такого UID получают NegativeHit без обращения к БД.
+// Запись снимается при Set этого заказа (например, когда consumer его сохранит),
+// при Delete и при истечении NegativeTTL.
+func (c *OrderCache) SetNotFound(uid string) {
+	if c.negativeTTL <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Заказ мог быть сохранён и закэширован, пока шёл запрос к БД
+	if _, exists := c.cache[uid]; exists {
+		return
+	}
+	// Для ограниченного кэша не даём негативным записям расти сверх лимита записей
+	if c.maxEntries > 0 && len(c.negative) >= c.maxEntries {
+		return
+	}
+	c.negative[uid] = time.Now().Add(c.negativeTTL)
+}
+
+// negativeStatus — статус поиска для UID, которого нет среди заказов в кэше
+func (c *OrderCache) negativeStatus(negative bool, notFoundUntil time.Time) LookupStatus {
+	if !negative || time.Now().After(notFoundUntil) {
+		return Miss
+	}
+	return NegativeHit
+}
+
+// sweepNegative удаляет истёкшие негативные записи
+func (c *OrderCache) sweepNegative(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for uid, until := range c.negative {
+		if now.After(until) {
+			delete(c.negative, uid)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+	"github.com/highdolen/L0/internal/resp"
+)
+
+// ErrSnapshotUnsupported — кэш не поддерживает снимки (например, внешний кэш хранит данные сам)
+var ErrSnapshotUnsupported = errors.New("кэш не поддерживает снимки")
+
+// RedisOptions — параметры внешнего кэша
+type RedisOptions struct {
+	// Prefix — префикс всех ключей кэша (по умолчанию "l0:")
+	Prefix string
+	// StaleGrace — сколько запись хранится после истечения TTL и отдаётся со статусом Stale
+	StaleGrace time.Duration
+	// NegativeTTL — сколько помнить, что заказа нет в БД (0 — отключено)
+	NegativeTTL time.Duration
+	// Codec — формат хранения записей (по умолчанию JSON)
+	Codec Codec
+	// Timeout — таймаут одной операции с сервером (по умолчанию 500ms)
+	Timeout time.Duration
+	// TTLPolicy вычисляет TTL записи по заказу (nil — у всех записей общий TTL)
+	TTLPolicy TTLPolicy
+}
+
+// RedisCache — кэш заказов во внешнем хранилище с протоколом RESP (Redis, Valkey и т.п.).
+// Общий для всех реплик сервиса: запись или инвалидация на одной реплике сразу
+// видна остальным. Истечение записей выполняет сервер по TTL ключа.
+// Ошибки сервера не прерывают работу: операция логируется и считается промахом.
+type RedisCache struct {
+	client *resp.Client
+	ttl    time.Duration
+	opts   RedisOptions
+
+	counters counters
+	events   *eventHub
+}
+
+// NewRedis создает внешний кэш поверх RESP-клиента. Клиент закрывается в Close.
+func NewRedis(client *resp.Client, ttl time.Duration, opts RedisOptions) *RedisCache {
+	if opts.Prefix == "" {
+		opts.Prefix = "l0:"
+	}
+	if opts.Codec == nil {
+		opts.Codec = JSONCodec{}
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 500 * time.Millisecond
+	}
+	return &RedisCache{
+		client: client,
+		ttl:    ttl,
+		opts:   opts,
+		events: newEventHub(),
+	}
+}
+
+func (c *RedisCache) orderKey(uid string) string {
+	return c.opts.Prefix + "order:" + uid
+}
+
+func (c *RedisCache) notFoundKey(uid string) string {
+	return c.opts.Prefix + "notfound:" + uid
+}
+
+func (c *RedisCache) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.opts.Timeout)
+}
+
+// fail учитывает и логирует ошибку обращения к серверу
+func (c *RedisCache) fail(op, uid string, err error) {
+	c.counters.errors.Add(1)
+	log.Printf("Ошибка внешнего кэша (%s %s): %v", op, uid, err)
+}
+
+// Get — получить заказ по UID; устаревшие записи считаются отсутствующими
+func (c *RedisCache) Get(uid string) (models.Order, bool) {
+	order, status := c.Lookup(uid)
+	if status != Hit {
+		return models.Order{}, false
+	}
+	return order, true
+}
+
+// Lookup — найти заказ по UID и сообщить, актуальна ли запись
+func (c *RedisCache) Lookup(uid string) (models.Order, LookupStatus) {
+	order, status := c.lookup(uid)
+	c.counters.lookup(status)
+	return order, status
+}
+
+// lookup читает запись заказа и негативную запись одной командой MGET
+func (c *RedisCache) lookup(uid string) (models.Order, LookupStatus) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	reply, err := c.client.Do(ctx, "MGET", c.orderKey(uid), c.notFoundKey(uid))
+	if err == nil && (reply.Kind != resp.Array || len(reply.Array) != 2) {
+		err = errors.New("неожиданный ответ на MGET")
+	}
+	if err != nil {
+		c.fail("lookup", uid, err)
+		return models.Order{}, Miss
+	}
+
+	if data := reply.Array[0]; !data.Null {
+		e, err := c.opts.Codec.Decode(data.Bytes())
+		if err != nil {
+			c.fail("decode", uid, err)
+			return models.Order{}, Miss
+		}
+		ttl := e.TTL
+		if ttl <= 0 {
+			ttl = c.ttl
+		}
+		switch age := time.Since(e.Timestamp); {
+		case age <= ttl:
+			return e.Order, Hit
+		case age <= ttl+c.opts.StaleGrace:
+			return e.Order, Stale
+		default:
+			return models.Order{}, Miss
+		}
+	}
+
+	if c.opts.NegativeTTL > 0 && !reply.Array[1].Null {
+		return models.Order{}, NegativeHit
+	}
+	return models.Order{}, Miss
+}
+
+// Set — сохранить заказ с TTL по политике или по умолчанию
+func (c *RedisCache) Set(uid string, order models.Order) {
+	c.SetWithTTL(uid, order, 0)
+}
+
+// SetWithTTL — сохранить заказ с собственным временем жизни (ttl <= 0 — как в Set).
+// Ключ живёт TTL плюс окно StaleGrace; негативная запись для этого UID снимается.
+func (c *RedisCache) SetWithTTL(uid string, order models.Order, ttl time.Duration) {
+	if ttl <= 0 && c.opts.TTLPolicy != nil {
+		ttl = c.opts.TTLPolicy(order)
+	}
+	entry := CodecEntry{Order: order, Timestamp: time.Now()}
+	if ttl > 0 && ttl != c.ttl {
+		entry.TTL = ttl
+	} else {
+		ttl = c.ttl
+	}
+
+	data, err := c.opts.Codec.Encode(entry)
+	if err != nil {
+		c.fail("encode", uid, err)
+		return
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	expire := strconv.FormatInt((ttl + c.opts.StaleGrace).Milliseconds(), 10)
+	replies, err := c.client.Pipeline(ctx,
+		[]string{"SET", c.orderKey(uid), string(data), "PX", expire},
+		[]string{"DEL", c.notFoundKey(uid)},
+	)
+	if err == nil {
+		err = firstError(replies)
+	}
+	if err != nil {
+		c.fail("set", uid, err)
+		return
+	}
+	c.counters.sets.Add(1)
+	c.events.publish(EventSet, uid, 0)
+}
+
+// SetNotFound — запомнить, что заказа нет в БД, на время NegativeTTL.
+// Если заказ уже есть в кэше, Lookup вернёт его, а не NegativeHit.
+func (c *RedisCache) SetNotFound(uid string) {
+	if c.opts.NegativeTTL <= 0 {
+		return
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	expire := strconv.FormatInt(c.opts.NegativeTTL.Milliseconds(), 10)
+	if _, err := c.client.Do(ctx, "SET", c.notFoundKey(uid), "1", "PX", expire); err != nil {
+		c.fail("set-not-found", uid, err)
+	}
+}
+
+// Delete — удалить заказ и негативную запись по UID
+func (c *RedisCache) Delete(uid string) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	reply, err := c.client.Do(ctx, "DEL", c.orderKey(uid), c.notFoundKey(uid))
+	if err != nil {
+		c.fail("delete", uid, err)
+		return
+	}
+	if reply.Int > 0 {
+		c.counters.deletes.Add(1)
+		c.events.publish(EventDelete, uid, 0)
+	}
+}
+
+// Invalidate — инвалидировать конкретный заказ
+func (c *RedisCache) Invalidate(uid string) {
+	c.Delete(uid)
+}
+
+// InvalidateAll — удалить все ключи с префиксом кэша. Ключи других
+// приложений на том же сервере не затрагиваются.
+func (c *RedisCache) InvalidateAll() {
+	removed, err := c.forEachKey(globEscape(c.opts.Prefix)+"*", func(ctx context.Context, keys []string) error {
+		_, err := c.client.Do(ctx, append([]string{"DEL"}, keys...)...)
+		return err
+	})
+	c.counters.deletes.Add(uint64(removed))
+	c.events.publish(EventFlush, "", removed)
+	if err != nil {
+		c.fail("invalidate-all", c.opts.Prefix+"*", err)
+	}
+	log.Printf("Внешний кэш очищен, удалено %d ключей", removed)
+}
+
+// forEachKey обходит ключи по шаблону SCAN MATCH и передаёт их в fn пачками
+func (c *RedisCache) forEachKey(pattern string, fn func(ctx context.Context, keys []string) error) (int, error) {
+	cursor, total := "0", 0
+	for {
+		ctx, cancel := c.context()
+		reply, err := c.client.Do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", "1000")
+		if err == nil && (reply.Kind != resp.Array || len(reply.Array) != 2) {
+			err = errors.New("неожиданный ответ на SCAN")
+		}
+		if err != nil {
+			cancel()
+			return total, err
+		}
+
+		cursor = reply.Array[0].Str
+		keys := make([]string, 0, len(reply.Array[1].Array))
+		for _, k := range reply.Array[1].Array {
+			keys = append(keys, k.Str)
+		}
+		if len(keys) > 0 {
+			if err := fn(ctx, keys); err != nil {
+				cancel()
+				return total, err
+			}
+			total += len(keys)
+		}
+		cancel()
+
+		if cursor == "0" {
+			return total, nil
+		}
+	}
+}
+
+// GetStats — статистика внешнего кэша. Число записей не подсчитывается
+// (для этого пришлось бы обойти все ключи на сервере), поэтому счётчики
+// записей равны -1; истечение и вытеснение выполняет сервер и они не видны.
+func (c *RedisCache) GetStats() CacheStats {
+	st := CacheStats{
+		Backend:         "redis",
+		TTLSeconds:      c.ttl.Seconds(),
+		Entries:         -1,
+		ExpiredEntries:  -1,
+		NegativeEntries: -1,
+	}
+	c.counters.fill(&st)
+	st.finish()
+	return st
+}
+
+// Subscribe — подписаться на события кэша. Видны только операции этой реплики:
+// истечение и вытеснение выполняет сервер, о них события не приходят.
+func (c *RedisCache) Subscribe(buffer int) *Subscription {
+	return c.events.subscribe(buffer)
+}
+
+// SaveSnapshot не поддерживается: данные и так хранятся на внешнем сервере
+func (c *RedisCache) SaveSnapshot(w io.Writer) error {
+	return ErrSnapshotUnsupported
+}
+
+// LoadSnapshot не поддерживается: данные и так хранятся на внешнем сервере
+func (c *RedisCache) LoadSnapshot(r io.Reader) (time.Time, error) {
+	return time.Time{}, ErrSnapshotUnsupported
+}
+
+// Close — закрыть соединения с сервером
+func (c *RedisCache) Close() {
+	c.client.Close()
+}
+
+func firstError(replies []resp.Value) error {
+	for _, r := range replies {
+		if err := r.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// globEscape экранирует спецсимволы шаблона SCAN MATCH
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
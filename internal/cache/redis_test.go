@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+	"github.com/highdolen/L0/internal/resp"
+	"github.com/highdolen/L0/internal/resp/resptest"
+)
+
+// newTestRedis запускает встроенный RESP-сервер и внешний кэш поверх него
+func newTestRedis(t *testing.T, ttl time.Duration, opts RedisOptions) (*RedisCache, *resptest.Server) {
+	t.Helper()
+	srv, err := resptest.NewServer()
+	if err != nil {
+		t.Fatalf("запуск RESP-сервера: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	c := NewRedis(resp.NewClient(srv.Addr(), resp.Options{}), ttl, opts)
+	t.Cleanup(c.Close)
+	return c, srv
+}
+
+// pttl — оставшееся время жизни ключа на сервере
+func pttl(t *testing.T, srv *resptest.Server, key string) time.Duration {
+	t.Helper()
+	client := resp.NewClient(srv.Addr(), resp.Options{})
+	defer client.Close()
+	v, err := client.Do(context.Background(), "PTTL", key)
+	if err != nil {
+		t.Fatalf("PTTL %s: %v", key, err)
+	}
+	return time.Duration(v.Int) * time.Millisecond
+}
+
+// putEntry записывает запись на сервер в обход RedisCache, как её записала бы другая реплика
+func putEntry(t *testing.T, srv *resptest.Server, key string, data []byte) {
+	t.Helper()
+	client := resp.NewClient(srv.Addr(), resp.Options{})
+	defer client.Close()
+	if _, err := client.Do(context.Background(), "SET", key, string(data)); err != nil {
+		t.Fatalf("SET %s: %v", key, err)
+	}
+}
+
+func TestRedisCacheRoundTrip(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec{}, GobCodec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			c, srv := newTestRedis(t, time.Hour, RedisOptions{Codec: codec})
+
+			order := benchOrder("a")
+			order.Delivery = models.Delivery{Name: "Test", City: "Moscow"}
+			order.Payment = models.Payment{Transaction: "a", Amount: 100}
+			c.Set("a", order)
+
+			if keys := srv.Keys(); !slices.Equal(keys, []string{"l0:order:a"}) {
+				t.Fatalf("ключи на сервере: %v", keys)
+			}
+
+			got, status := c.Lookup("a")
+			if status != Hit {
+				t.Fatalf("Lookup после Set: %v, ожидался Hit", status)
+			}
+			if got.OrderUID != order.OrderUID || got.TrackNumber != order.TrackNumber ||
+				got.Delivery.City != order.Delivery.City || got.Payment.Amount != order.Payment.Amount ||
+				len(got.Items) != 1 || got.Items[0].Name != order.Items[0].Name ||
+				!got.DateCreated.Equal(order.DateCreated) {
+				t.Errorf("заказ после кодека %s: %+v, ожидался %+v", codec.Name(), got, order)
+			}
+
+			c.Delete("a")
+			if _, ok := c.Get("a"); ok {
+				t.Errorf("заказ найден после Delete")
+			}
+			if keys := srv.Keys(); len(keys) != 0 {
+				t.Errorf("после Delete на сервере остались ключи: %v", keys)
+			}
+		})
+	}
+}
+
+func TestRedisCacheKeyTTL(t *testing.T) {
+	const ttl, grace = time.Hour, 10 * time.Minute
+	c, srv := newTestRedis(t, ttl, RedisOptions{StaleGrace: grace})
+
+	c.Set("default", benchOrder("default"))
+	c.SetWithTTL("custom", benchOrder("custom"), time.Minute)
+
+	// Ключ живёт TTL записи плюс окно StaleGrace
+	tests := []struct {
+		key  string
+		want time.Duration
+	}{
+		{"l0:order:default", ttl + grace},
+		{"l0:order:custom", time.Minute + grace},
+	}
+	for _, tt := range tests {
+		if got := pttl(t, srv, tt.key); got > tt.want || got < tt.want-time.Second {
+			t.Errorf("PTTL %s = %v, ожидалось %v", tt.key, got, tt.want)
+		}
+	}
+
+	// По истечении срока ключ удаляет сервер
+	srv.SetClock(func() time.Time { return time.Now().Add(ttl + grace + time.Second) })
+	if _, status := c.Lookup("default"); status != Miss {
+		t.Errorf("Lookup после истечения ключа: %v, ожидался Miss", status)
+	}
+}
+
+func TestRedisCacheEntryAge(t *testing.T) {
+	const ttl, grace = time.Hour, 10 * time.Minute
+	c, srv := newTestRedis(t, ttl, RedisOptions{StaleGrace: grace})
+	codec := JSONCodec{}
+
+	tests := []struct {
+		name string
+		age  time.Duration
+		ttl  time.Duration // собственный TTL записи
+		want LookupStatus
+	}{
+		{"свежая", time.Minute, 0, Hit},
+		{"в окне StaleGrace", ttl + time.Minute, 0, Stale},
+		{"за окном StaleGrace", ttl + grace + time.Minute, 0, Miss},
+		{"собственный TTL истёк", 2 * time.Minute, time.Minute, Stale},
+		{"собственный TTL длиннее общего", ttl + grace + time.Minute, 2 * ttl, Hit},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid := "order_" + strconv.Itoa(i)
+			data, err := codec.Encode(CodecEntry{Order: benchOrder(uid), Timestamp: time.Now().Add(-tt.age), TTL: tt.ttl})
+			if err != nil {
+				t.Fatal(err)
+			}
+			putEntry(t, srv, "l0:order:"+uid, data)
+
+			if _, status := c.Lookup(uid); status != tt.want {
+				t.Errorf("Lookup = %v, ожидалось %v", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisCacheNegativeEntries(t *testing.T) {
+	c, srv := newTestRedis(t, time.Hour, RedisOptions{NegativeTTL: time.Minute})
+
+	c.SetNotFound("a")
+	if _, status := c.Lookup("a"); status != NegativeHit {
+		t.Fatalf("Lookup после SetNotFound: %v, ожидался NegativeHit", status)
+	}
+	if got := pttl(t, srv, "l0:notfound:a"); got > time.Minute || got < time.Minute-time.Second {
+		t.Errorf("PTTL негативной записи = %v, ожидалась минута", got)
+	}
+
+	// Сохранение заказа снимает негативную запись
+	c.Set("a", benchOrder("a"))
+	if keys := srv.Keys(); !slices.Equal(keys, []string{"l0:order:a"}) {
+		t.Errorf("ключи после Set: %v", keys)
+	}
+
+	c.Delete("a")
+	if _, status := c.Lookup("a"); status != Miss {
+		t.Errorf("Lookup после Delete: %v, ожидался Miss", status)
+	}
+}
+
+func TestRedisCacheCorruptEntry(t *testing.T) {
+	c, srv := newTestRedis(t, time.Hour, RedisOptions{Codec: GobCodec{}})
+
+	putEntry(t, srv, "l0:order:a", []byte("not gob"))
+	if _, status := c.Lookup("a"); status != Miss {
+		t.Errorf("Lookup повреждённой записи: %v, ожидался Miss", status)
+	}
+	if st := c.GetStats(); st.Errors != 1 {
+		t.Errorf("ошибок в статистике: %d, ожидалась 1", st.Errors)
+	}
+}
+
+func TestRedisCacheInvalidateAll(t *testing.T) {
+	c, srv := newTestRedis(t, time.Hour, RedisOptions{NegativeTTL: time.Minute})
+
+	for i := 0; i < 2500; i++ {
+		c.Set("order_"+strconv.Itoa(i), benchOrder("order_"+strconv.Itoa(i)))
+	}
+	c.SetNotFound("missing")
+	putEntry(t, srv, "other:key", []byte("x"))
+
+	c.InvalidateAll()
+
+	// Ключи других приложений на том же сервере не удаляются
+	if keys := srv.Keys(); !slices.Equal(keys, []string{"other:key"}) {
+		t.Errorf("после InvalidateAll остались ключи: %v", keys)
+	}
+}
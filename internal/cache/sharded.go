@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"log"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// ShardedOptions — параметры шардированного кэша
+type ShardedOptions struct {
+	// Shards — число шардов (по умолчанию 16)
+	Shards int
+	// MaxEntries и MaxBytes — общие лимиты, делятся поровну между шардами (0 — без ограничения)
+	MaxEntries int
+	MaxBytes   int64
+	// NewEviction создает стратегию вытеснения для каждого шарда (по умолчанию LRU)
+	NewEviction func() EvictionPolicy
+	// StaleGrace — окно, в течение которого устаревшие записи отдаются со статусом Stale
+	StaleGrace time.Duration
+	// NegativeTTL — сколько помнить, что заказа нет в БД (0 — отключено)
+	NegativeTTL time.Duration
+	// TTLPolicy вычисляет TTL записи по заказу (nil — у всех записей общий TTL)
+	TTLPolicy TTLPolicy
+}
+
+// ShardedCache — кэш заказов, разбитый на независимые шарды с собственными
+// блокировками. Ключ попадает в шард по хэшу, поэтому запросы к разным
+// заказам не конкурируют за один мьютекс. Устаревшие записи удаляются
+// инкрементально: за один тик очистки обходится только один шард.
+type ShardedCache struct {
+	shards   []*OrderCache
+	events   *eventHub // общий для всех шардов
+	ttl      time.Duration
+	stopChan chan bool
+}
+
+// NewSharded создает шардированный кэш с указанным TTL
+func NewSharded(ttl time.Duration, opts ShardedOptions) *ShardedCache {
+	n := opts.Shards
+	if n <= 0 {
+		n = 16
+	}
+
+	c := &ShardedCache{
+		shards:   make([]*OrderCache, n),
+		events:   newEventHub(),
+		ttl:      ttl,
+		stopChan: make(chan bool),
+	}
+	for i := range c.shards {
+		shardOpts := Options{StaleGrace: opts.StaleGrace, NegativeTTL: opts.NegativeTTL, TTLPolicy: opts.TTLPolicy}
+		if opts.MaxEntries > 0 {
+			shardOpts.MaxEntries = (opts.MaxEntries + n - 1) / n
+		}
+		if opts.MaxBytes > 0 {
+			shardOpts.MaxBytes = (opts.MaxBytes + int64(n) - 1) / int64(n)
+		}
+		if opts.NewEviction != nil && (shardOpts.MaxEntries > 0 || shardOpts.MaxBytes > 0) {
+			shardOpts.Eviction = opts.NewEviction()
+		}
+		c.shards[i] = newOrderCache(ttl, shardOpts)
+		c.shards[i].events = c.events
+	}
+
+	// Запускаем горутину для инкрементальной очистки устаревших записей
+	go c.cleanupExpired()
+
+	return c
+}
+
+// shard возвращает шард для ключа
+func (c *ShardedCache) shard(uid string) *OrderCache {
+	return c.shards[c.shardIndex(uid)]
+}
+
+// shardIndex — номер шарда для ключа (FNV-1a без аллокаций)
+func (c *ShardedCache) shardIndex(uid string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(uid); i++ {
+		h ^= uint32(uid[i])
+		h *= 16777619
+	}
+	return int(h % uint32(len(c.shards)))
+}
+
+// Get — получить заказ по UID с проверкой TTL
+func (c *ShardedCache) Get(uid string) (models.Order, bool) {
+	return c.shard(uid).Get(uid)
+}
+
+// Lookup — найти заказ по UID и сообщить, актуальна ли запись
+func (c *ShardedCache) Lookup(uid string) (models.Order, LookupStatus) {
+	return c.shard(uid).Lookup(uid)
+}
+
+// Set — добавить или обновить заказ
+func (c *ShardedCache) Set(uid string, order models.Order) {
+	c.shard(uid).Set(uid, order)
+}
+
+// SetWithTTL — добавить или обновить заказ с собственным временем жизни
+func (c *ShardedCache) SetWithTTL(uid string, order models.Order, ttl time.Duration) {
+	c.shard(uid).SetWithTTL(uid, order, ttl)
+}
+
+// SetNotFound — запомнить, что заказа нет в БД
+func (c *ShardedCache) SetNotFound(uid string) {
+	c.shard(uid).SetNotFound(uid)
+}
+
+// Delete — удалить заказ по UID
+func (c *ShardedCache) Delete(uid string) {
+	c.shard(uid).Delete(uid)
+}
+
+// Invalidate — инвалидировать конкретный заказ
+func (c *ShardedCache) Invalidate(uid string) {
+	c.Delete(uid)
+}
+
+// InvalidateAll — очистить весь кэш
+func (c *ShardedCache) InvalidateAll() {
+	removed := 0
+	for _, s := range c.shards {
+		removed += s.clear()
+	}
+	c.events.publish(EventFlush, "", removed)
+	log.Println("Кэш полностью очищен")
+}
+
+// GetStats — получить суммарную статистику по всем шардам
+func (c *ShardedCache) GetStats() CacheStats {
+	st := CacheStats{
+		Backend:    "sharded",
+		Shards:     len(c.shards),
+		TTLSeconds: c.ttl.Seconds(),
+	}
+	now := time.Now()
+	for _, s := range c.shards {
+		st.add(s.collectStats(now))
+	}
+	st.finish()
+	return st
+}
+
+// cleanupExpired — горутина инкрементальной очистки: каждый шард
+// проверяется раз в TTL/2, но шарды обходятся по очереди
+func (c *ShardedCache) cleanupExpired() {
+	interval := c.ttl / 2 / time.Duration(len(c.shards))
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	next := 0
+	removed := 0
+	for {
+		select {
+		case <-ticker.C:
+			removed += c.shards[next].sweep(time.Now())
+			next = (next + 1) % len(c.shards)
+			// Логируем итог после полного прохода по шардам
+			if next == 0 && removed > 0 {
+				log.Printf("Удалено %d устаревших записей из кэша", removed)
+				removed = 0
+			}
+
+		case <-c.stopChan:
+			return
+		}
+	}
+}
+
+// Subscribe — подписаться на события всех шардов
+func (c *ShardedCache) Subscribe(buffer int) *Subscription {
+	return c.events.subscribe(buffer)
+}
+
+// Close — остановить горутину очистки
+func (c *ShardedCache) Close() {
+	close(c.stopChan)
+}
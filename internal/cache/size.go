@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"unsafe"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// entryOverhead — приблизительные накладные расходы map и служебных структур на одну запись
+const entryOverhead = 128
+
+// estimateSize оценивает объём памяти, занимаемый заказом в кэше (в байтах).
+// Учитываются фиксированные размеры структур и длины всех строк; оценка
+// приблизительная и нужна только для ограничения MaxBytes.
+func estimateSize(key string, order models.Order) int64 {
+	size := int64(entryOverhead + len(key))
+	size += int64(unsafe.Sizeof(CacheEntry{}))
+	size += int64(len(order.OrderUID) + len(order.TrackNumber) + len(order.Entry) + len(order.Locale) +
+		len(order.InternalSignature) + len(order.CustomerID) + len(order.DeliveryService) +
+		len(order.Shardkey) + len(order.OofShard))
+
+	d := order.Delivery
+	size += int64(len(d.Name) + len(d.Phone) + len(d.Zip) + len(d.City) + len(d.Address) + len(d.Region) + len(d.Email))
+
+	p := order.Payment
+	size += int64(len(p.Transaction) + len(p.RequestID) + len(p.Currency) + len(p.Provider) + len(p.Bank))
+
+	size += int64(cap(order.Items)) * int64(unsafe.Sizeof(models.Item{}))
+	for _, item := range order.Items {
+		size += int64(len(item.TrackNumber) + len(item.Rid) + len(item.Name) + len(item.Size) +
+			len(item.Brand) + len(item.OrderUID))
+	}
+	return size
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// Формат файла снимка:
+//
+//	magic "L0CACHE" | версия (1 байт) | gzip( gob(snapshotHeader) gob(snapshotEntry)... )
+//
+// Целостность проверяется контрольной суммой gzip (CRC-32), поэтому
+// обрезанный или повреждённый файл отклоняется целиком.
+const (
+	snapshotMagic   = "L0CACHE"
+	snapshotVersion = 1
+)
+
+// ErrSnapshotCorrupt — файл снимка повреждён или имеет неизвестный формат
+var ErrSnapshotCorrupt = errors.New("снимок кэша повреждён")
+
+// Snapshotter — кэш, который умеет сохранять и восстанавливать своё содержимое
+type Snapshotter interface {
+	// SaveSnapshot записывает актуальные записи кэша в w
+	SaveSnapshot(w io.Writer) error
+	// LoadSnapshot загружает записи из r и возвращает момент создания снимка
+	LoadSnapshot(r io.Reader) (time.Time, error)
+}
+
+type snapshotHeader struct {
+	CreatedAt time.Time
+	Count     int
+}
+
+type snapshotEntry struct {
+	UID       string
+	Order     models.Order
+	Timestamp time.Time
+	// TTL — собственное время жизни записи (0 — TTL кэша)
+	TTL time.Duration
+}
+
+// SaveSnapshot записывает в w все записи, которые ещё можно отдавать (Hit и Stale).
+// Негативные записи не сохраняются.
+func (c *OrderCache) SaveSnapshot(w io.Writer) error {
+	return writeSnapshot(w, time.Now(), c.entries(time.Now()))
+}
+
+// LoadSnapshot загружает записи из снимка, сохраняя их исходные временные метки:
+// TTL продолжает отсчитываться с момента первоначального кэширования,
+// а окончательно устаревшие записи пропускаются
+func (c *OrderCache) LoadSnapshot(r io.Reader) (time.Time, error) {
+	return readSnapshot(r, func(e snapshotEntry) { c.restore(e) })
+}
+
+// entries копирует записи, не устаревшие окончательно на момент now
+func (c *OrderCache) entries(now time.Time) []snapshotEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries := make([]snapshotEntry, 0, len(c.cache))
+	for uid, entry := range c.cache {
+		if c.status(entry, now) == Miss {
+			continue
+		}
+		entries = append(entries, snapshotEntry{UID: uid, Order: entry.Order, Timestamp: entry.Timestamp, TTL: entry.TTL})
+	}
+	return entries
+}
+
+// restore добавляет запись из снимка, если она не устарела и не была
+// перезаписана более свежими данными
+func (c *OrderCache) restore(e snapshotEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.status(CacheEntry{Timestamp: e.Timestamp, TTL: e.TTL}, time.Now()) == Miss {
+		return
+	}
+	if cur, exists := c.cache[e.UID]; exists && !cur.Timestamp.Before(e.Timestamp) {
+		return
+	}
+	c.set(e.UID, e.Order, e.Timestamp, e.TTL)
+}
+
+// SaveSnapshot записывает записи всех шардов в единый снимок
+func (c *ShardedCache) SaveSnapshot(w io.Writer) error {
+	now := time.Now()
+	var entries []snapshotEntry
+	for _, s := range c.shards {
+		entries = append(entries, s.entries(now)...)
+	}
+	return writeSnapshot(w, now, entries)
+}
+
+// LoadSnapshot загружает снимок, распределяя записи по шардам.
+// Снимок не зависит от числа шардов и совместим с OrderCache.
+func (c *ShardedCache) LoadSnapshot(r io.Reader) (time.Time, error) {
+	return readSnapshot(r, func(e snapshotEntry) { c.shard(e.UID).restore(e) })
+}
+
+func writeSnapshot(w io.Writer, createdAt time.Time, entries []snapshotEntry) error {
+	if _, err := io.WriteString(w, snapshotMagic); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte{snapshotVersion}); err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	enc := gob.NewEncoder(zw)
+	if err := enc.Encode(snapshotHeader{CreatedAt: createdAt, Count: len(entries)}); err != nil {
+		return fmt.Errorf("ошибка записи заголовка снимка: %w", err)
+	}
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			return fmt.Errorf("ошибка записи заказа %s в снимок: %w", entries[i].UID, err)
+		}
+	}
+	return zw.Close()
+}
+
+// readSnapshot проверяет снимок целиком и только затем передаёт записи в apply,
+// чтобы повреждённый файл не оставил кэш частично заполненным
+func readSnapshot(r io.Reader, apply func(snapshotEntry)) (time.Time, error) {
+	prefix := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	if string(prefix[:len(snapshotMagic)]) != snapshotMagic {
+		return time.Time{}, fmt.Errorf("%w: неизвестный формат", ErrSnapshotCorrupt)
+	}
+	if v := prefix[len(snapshotMagic)]; v != snapshotVersion {
+		return time.Time{}, fmt.Errorf("%w: неподдерживаемая версия %d", ErrSnapshotCorrupt, v)
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	defer zr.Close()
+
+	dec := gob.NewDecoder(zr)
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	if header.Count < 0 {
+		return time.Time{}, fmt.Errorf("%w: некорректное число записей", ErrSnapshotCorrupt)
+	}
+
+	entries := make([]snapshotEntry, 0, min(header.Count, 1<<16))
+	for i := 0; i < header.Count; i++ {
+		var e snapshotEntry
+		if err := dec.Decode(&e); err != nil {
+			return time.Time{}, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+		}
+		entries = append(entries, e)
+	}
+	// Дочитываем поток до конца, чтобы gzip сверил контрольную сумму
+	if _, err := io.Copy(io.Discard, zr); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	for _, e := range entries {
+		apply(e)
+	}
+	return header.CreatedAt, nil
+}
+
+// SaveSnapshotFile атомарно записывает снимок в файл: данные пишутся во
+// временный файл рядом и переименовываются только после успешной записи
+func SaveSnapshotFile(path string, s Snapshotter) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	bw := bufio.NewWriter(tmp)
+	if err := s.SaveSnapshot(bw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshotFile загружает снимок из файла и возвращает момент его создания
+func LoadSnapshotFile(path string, s Snapshotter) (time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	return s.LoadSnapshot(bufio.NewReader(f))
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// snapshotBytes сохраняет снимок кэша с заказами uids
+func snapshotBytes(t *testing.T, uids ...string) []byte {
+	t.Helper()
+	c := New(time.Hour)
+	defer c.Close()
+	for _, uid := range uids {
+		c.Set(uid, benchOrder(uid))
+	}
+
+	var buf bytes.Buffer
+	if err := c.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	data := snapshotBytes(t, "a", "b", "c")
+
+	for _, tc := range []struct {
+		name  string
+		cache interface {
+			Snapshotter
+			benchCache
+		}
+	}{
+		{"OrderCache", New(time.Hour)},
+		{"ShardedCache", NewSharded(time.Hour, ShardedOptions{Shards: 4})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer tc.cache.Close()
+			createdAt, err := tc.cache.LoadSnapshot(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("LoadSnapshot: %v", err)
+			}
+			if time.Since(createdAt) > time.Minute {
+				t.Errorf("время создания снимка %v, ожидалось недавнее", createdAt)
+			}
+			for _, uid := range []string{"a", "b", "c"} {
+				order, ok := tc.cache.Get(uid)
+				if !ok {
+					t.Fatalf("заказ %s не восстановлен из снимка", uid)
+				}
+				if order.TrackNumber != "TRACK"+uid {
+					t.Errorf("заказ %s восстановлен с track_number %q", uid, order.TrackNumber)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSnapshotRejectsCorrupt(t *testing.T) {
+	data := snapshotBytes(t, "a", "b", "c")
+	prefixLen := len(snapshotMagic) + 1
+
+	flip := func(i int) []byte {
+		b := bytes.Clone(data)
+		b[i] ^= 0xff
+		return b
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"пустой файл", nil},
+		{"только заголовок формата", data[:prefixLen]},
+		{"неизвестный формат", append([]byte("NOTACHE"), data[len(snapshotMagic):]...)},
+		{"неизвестная версия", flip(len(snapshotMagic))},
+		{"обрезан посередине", data[:len(data)/2]},
+		{"обрезана контрольная сумма gzip", data[:len(data)-4]},
+		{"испорчена контрольная сумма gzip", flip(len(data) - 5)},
+		{"испорчены сжатые данные", flip(prefixLen + 20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(time.Hour)
+			defer c.Close()
+
+			_, err := c.LoadSnapshot(bytes.NewReader(tt.data))
+			if !errors.Is(err, ErrSnapshotCorrupt) {
+				t.Fatalf("LoadSnapshot вернул %v, ожидалась ErrSnapshotCorrupt", err)
+			}
+			// Повреждённый снимок не должен частично заполнить кэш
+			for _, uid := range []string{"a", "b", "c"} {
+				if _, ok := c.Get(uid); ok {
+					t.Errorf("заказ %s попал в кэш из повреждённого снимка", uid)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats — статистика кэша. Набор полей одинаков для всех реализаций:
+// то, что реализация посчитать не может, остаётся нулевым (для счётчиков
+// записей внешнего кэша — -1).
+type CacheStats struct {
+	// Backend — реализация кэша: memory, sharded или redis
+	Backend string `json:"backend"`
+	// Shards — число шардов (1 для memory, 0 для redis)
+	Shards     int     `json:"shards"`
+	TTLSeconds float64 `json:"ttl_seconds"`
+
+	// Entries — записи заказов в кэше, включая устаревшие, но ещё не удалённые
+	Entries int `json:"entries"`
+	// ExpiredEntries — записи с истёкшим TTL (в том числе в окне StaleGrace)
+	ExpiredEntries int `json:"expired_entries"`
+	// NegativeEntries — отметки об отсутствующих в БД заказах
+	NegativeEntries int `json:"negative_entries"`
+
+	// Результаты Lookup
+	Hits         uint64 `json:"hits"`
+	StaleHits    uint64 `json:"stale_hits"`
+	NegativeHits uint64 `json:"negative_hits"`
+	Misses       uint64 `json:"misses"`
+	// HitRatio — доля обращений, обслуженных без загрузки из БД
+	HitRatio float64 `json:"hit_ratio"`
+
+	// Изменения содержимого
+	Sets        uint64 `json:"sets"`
+	Deletes     uint64 `json:"deletes"`
+	Expirations uint64 `json:"expirations"`
+	Evictions   uint64 `json:"evictions"`
+
+	// Ограничения размера (EvictionPolicy пусто, если кэш не ограничен)
+	EvictionPolicy string `json:"eviction_policy"`
+	MaxEntries     int    `json:"max_entries"`
+	MaxBytes       int64  `json:"max_bytes"`
+	// ApproxBytes — приблизительный объём записей заказов в памяти
+	ApproxBytes int64 `json:"approx_bytes"`
+
+	// Errors — ошибки обращения к внешнему кэшу
+	Errors uint64 `json:"errors"`
+
+	// Loads — загрузки заказов из БД при промахе; заполняет сервисный слой
+	Loads LoadStats `json:"loads"`
+}
+
+// LoadStats — число и длительность загрузок заказов из БД при промахе
+type LoadStats struct {
+	Count        uint64  `json:"count"`
+	Errors       uint64  `json:"errors"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+}
+
+// finish вычисляет производные показатели
+func (st *CacheStats) finish() {
+	served := st.Hits + st.StaleHits + st.NegativeHits
+	if total := served + st.Misses; total > 0 {
+		st.HitRatio = float64(served) / float64(total)
+	}
+}
+
+// add суммирует статистику шарда в общую
+func (st *CacheStats) add(o CacheStats) {
+	st.Entries += o.Entries
+	st.ExpiredEntries += o.ExpiredEntries
+	st.NegativeEntries += o.NegativeEntries
+	st.Hits += o.Hits
+	st.StaleHits += o.StaleHits
+	st.NegativeHits += o.NegativeHits
+	st.Misses += o.Misses
+	st.Sets += o.Sets
+	st.Deletes += o.Deletes
+	st.Expirations += o.Expirations
+	st.Evictions += o.Evictions
+	st.ApproxBytes += o.ApproxBytes
+	if o.EvictionPolicy != "" {
+		st.EvictionPolicy = o.EvictionPolicy
+		st.MaxEntries += o.MaxEntries
+		st.MaxBytes += o.MaxBytes
+	}
+	st.Errors += o.Errors
+}
+
+// counters — счётчики операций кэша, общие для всех реализаций
+type counters struct {
+	hits, staleHits, negativeHits, misses atomic.Uint64
+	sets, deletes, expirations, evictions atomic.Uint64
+	errors                                atomic.Uint64
+}
+
+// lookup учитывает результат Lookup
+func (c *counters) lookup(status LookupStatus) {
+	switch status {
+	case Hit:
+		c.hits.Add(1)
+	case Stale:
+		c.staleHits.Add(1)
+	case NegativeHit:
+		c.negativeHits.Add(1)
+	default:
+		c.misses.Add(1)
+	}
+}
+
+// fill переносит значения счётчиков в статистику
+func (c *counters) fill(st *CacheStats) {
+	st.Hits = c.hits.Load()
+	st.StaleHits = c.staleHits.Load()
+	st.NegativeHits = c.negativeHits.Load()
+	st.Misses = c.misses.Load()
+	st.Sets = c.sets.Load()
+	st.Deletes = c.deletes.Load()
+	st.Expirations = c.expirations.Load()
+	st.Evictions = c.evictions.Load()
+	st.Errors = c.errors.Load()
+}
+
+// LoadRecorder накапливает статистику загрузок из БД
+type LoadRecorder struct {
+	count, errors   atomic.Uint64
+	total, maxNanos atomic.Int64
+}
+
+// Record учитывает одну загрузку
+func (r *LoadRecorder) Record(d time.Duration, err error) {
+	r.count.Add(1)
+	if err != nil {
+		r.errors.Add(1)
+	}
+	r.total.Add(int64(d))
+	for {
+		cur := r.maxNanos.Load()
+		if int64(d) <= cur || r.maxNanos.CompareAndSwap(cur, int64(d)) {
+			return
+		}
+	}
+}
+
+// Stats возвращает накопленную статистику загрузок
+func (r *LoadRecorder) Stats() LoadStats {
+	st := LoadStats{
+		Count:        r.count.Load(),
+		Errors:       r.errors.Load(),
+		MaxLatencyMs: float64(r.maxNanos.Load()) / float64(time.Millisecond),
+	}
+	if st.Count > 0 {
+		st.AvgLatencyMs = float64(r.total.Load()) / float64(st.Count) / float64(time.Millisecond)
+	}
+	return st
+}
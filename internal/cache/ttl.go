@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"sort"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// TTLPolicy вычисляет время жизни записи по содержимому заказа.
+// Нулевое значение означает TTL кэша по умолчанию.
+type TTLPolicy func(order models.Order) time.Duration
+
+// AgeTTL — TTL для заказов, созданных не более MaxAge назад
+type AgeTTL struct {
+	MaxAge time.Duration
+	TTL    time.Duration
+}
+
+// TTLPolicyOptions — правила встроенной политики TTL
+type TTLPolicyOptions struct {
+	// ByAge — TTL в зависимости от возраста заказа (по DateCreated).
+	// Применяется первое правило с подходящим MaxAge.
+	ByAge []AgeTTL
+	// FinalStatuses — статусы товаров, означающие, что заказ завершён (например, доставлен)
+	FinalStatuses []int
+	// FinalTTL — TTL заказа, все товары которого в одном из FinalStatuses.
+	// Имеет приоритет над ByAge: завершённые заказы читают редко.
+	FinalTTL time.Duration
+}
+
+// NewTTLPolicy создает политику TTL по возрасту и статусу заказа.
+// Если правил нет, возвращает nil — все записи получают TTL по умолчанию.
+func NewTTLPolicy(opts TTLPolicyOptions) TTLPolicy {
+	if len(opts.ByAge) == 0 && (opts.FinalTTL <= 0 || len(opts.FinalStatuses) == 0) {
+		return nil
+	}
+
+	byAge := append([]AgeTTL(nil), opts.ByAge...)
+	sort.Slice(byAge, func(i, j int) bool { return byAge[i].MaxAge < byAge[j].MaxAge })
+
+	final := make(map[int]bool, len(opts.FinalStatuses))
+	for _, status := range opts.FinalStatuses {
+		final[status] = true
+	}
+
+	return func(order models.Order) time.Duration {
+		if opts.FinalTTL > 0 && len(final) > 0 && allItemsIn(order.Items, final) {
+			return opts.FinalTTL
+		}
+		age := time.Since(order.DateCreated)
+		for _, rule := range byAge {
+			if age <= rule.MaxAge {
+				return rule.TTL
+			}
+		}
+		return 0
+	}
+}
+
+func allItemsIn(items []models.Item, statuses map[int]bool) bool {
+	if len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		if !statuses[item.Status] {
+			return false
+		}
+	}
+	return true
+}
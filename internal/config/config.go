@@ -1,11 +1,16 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type Config struct {
 	DB     DBConfig
 	Kafka  KafkaConfig
 	Server ServerConfig
+	Cache  CacheConfig
+	Warmup WarmupConfig
 }
 
 type DBConfig struct {
@@ -15,26 +20,204 @@ type DBConfig struct {
 	Password string
 	Name     string
 	SSLMode  string
+	// Migrate — проверка схемы при старте: check (по умолчанию; отказаться от запуска,
+	// если не все миграции применены), up (применить недостающие) или off (не проверять)
+	Migrate string
+}
+
+// DSN — строка подключения к PostgreSQL
+func (c DBConfig) DSN() string {
+	return "postgres://" + c.User + ":" + c.Password + "@" + c.Host + ":" + c.Port + "/" + c.Name + "?sslmode=" + c.SSLMode
+}
+
+// Validate проверяет параметры подключения к БД
+func (c DBConfig) Validate() error {
+	if c.Host == "" || c.Port == "" || c.User == "" || c.Password == "" || c.Name == "" {
+		return fmt.Errorf("database configuration is incomplete")
+	}
+	switch c.Migrate {
+	case "off", "check", "up":
+	default:
+		return fmt.Errorf("unknown database migrate mode %q", c.Migrate)
+	}
+	return nil
 }
 
 type KafkaConfig struct {
-	Broker string
+	Broker  string
+	Topic   string
+	GroupID string
+	// DeadLetterTopic — топик для сообщений, которые не удалось обработать.
+	// Пустое значение отключает отправку в DLQ.
+	DeadLetterTopic string
+	// InvalidationTopic — топик для рассылки инвалидаций кэша между репликами.
+	// Пустое значение отключает рассылку.
+	InvalidationTopic string
+	// StatusTopic — топик событий изменения статуса заказов.
+	// Пустое значение отключает обработку статусов.
+	StatusTopic string
+	// StatusGroupID — consumer group для топика статусов (по умолчанию GroupID + "-status")
+	StatusGroupID string
+	// Повторы сохранения заказа при временных ошибках БД
+	RetryMaxAttempts    int
+	RetryInitialBackoff time.Duration
+	RetryMaxBackoff     time.Duration
+	// CommitInterval — период пакетного коммита offset'ов (0 — после каждого сообщения)
+	CommitInterval time.Duration
+	// Workers — число параллельных обработчиков сообщений
+	Workers int
+	// BatchSize и BatchTimeout — размер и время накопления пакета заказов для записи в БД
+	BatchSize    int
+	BatchTimeout time.Duration
 }
 
 type ServerConfig struct {
 	Port string
+	// NodeID — идентификатор реплики сервиса (по умолчанию hostname-pid)
+	NodeID string
+}
+
+type CacheConfig struct {
+	// Backend — реализация кэша: memory (один мьютекс), sharded или redis (внешний, общий для реплик)
+	Backend string
+	// Shards — число шардов для backend=sharded
+	Shards int
+	TTL    time.Duration
+	// MaxEntries и MaxBytes ограничивают размер кэша (0 — без ограничения)
+	MaxEntries int
+	MaxBytes   int64
+	// EvictionPolicy — стратегия вытеснения: lru или lfu
+	EvictionPolicy string
+	// StaleGrace — окно stale-while-revalidate после истечения TTL (0 — отключено)
+	StaleGrace time.Duration
+	// NegativeTTL — время жизни записи об отсутствующем заказе (0 — отключено)
+	NegativeTTL time.Duration
+	// SnapshotPath — файл снимка кэша для быстрого перезапуска (пусто — отключено)
+	SnapshotPath string
+	// Redis — подключение к внешнему кэшу для backend=redis
+	Redis RedisConfig
+	// TTLByAge — TTL записей в зависимости от возраста заказа (пусто — у всех TTL)
+	TTLByAge []AgeTTL
+	// FinalStatuses и FinalTTL — TTL заказов, все товары которых в одном из этих статусов
+	FinalStatuses []int
+	FinalTTL      time.Duration
+	// EventLog — писать в лог события кэша (set, delete, expire, evict, flush)
+	EventLog bool
+}
+
+// AgeTTL — TTL для заказов не старше MaxAge
+type AgeTTL struct {
+	MaxAge time.Duration
+	TTL    time.Duration
+}
+
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	// Prefix — префикс ключей кэша на сервере
+	Prefix string
+	// Codec — формат хранения записей: json или gob
+	Codec string
+	// Timeout — таймаут одной операции с сервером
+	Timeout time.Duration
+}
+
+type WarmupConfig struct {
+	// Mode — режим прогрева кэша при старте: full, recent или none
+	Mode string
+	// RecentDays — за сколько последних дней загружать заказы в режиме recent
+	RecentDays int
 }
 
 // Validate проверяет, что все обязательные поля заполнены
 func (c *Config) Validate() error {
-	if c.DB.Host == "" || c.DB.Port == "" || c.DB.User == "" || c.DB.Password == "" || c.DB.Name == "" {
-		return fmt.Errorf("database configuration is incomplete")
+	if err := c.DB.Validate(); err != nil {
+		return err
 	}
 	if c.Kafka.Broker == "" {
 		return fmt.Errorf("kafka broker address is missing")
 	}
+	if c.Kafka.Topic == "" || c.Kafka.GroupID == "" {
+		return fmt.Errorf("kafka topic or group id is missing")
+	}
+	if c.Kafka.DeadLetterTopic != "" && c.Kafka.DeadLetterTopic == c.Kafka.Topic {
+		return fmt.Errorf("kafka dead-letter topic must differ from the source topic")
+	}
+	if c.Kafka.StatusTopic != "" {
+		switch c.Kafka.StatusTopic {
+		case c.Kafka.Topic, c.Kafka.DeadLetterTopic, c.Kafka.InvalidationTopic:
+			return fmt.Errorf("kafka status topic must differ from the other topics")
+		}
+		if c.Kafka.StatusGroupID == "" || c.Kafka.StatusGroupID == c.Kafka.GroupID {
+			return fmt.Errorf("kafka status group id must be set and differ from the orders group id")
+		}
+	}
+	if c.Kafka.RetryMaxAttempts < 1 {
+		return fmt.Errorf("kafka retry max attempts must be positive")
+	}
+	if c.Kafka.RetryInitialBackoff <= 0 || c.Kafka.RetryMaxBackoff < c.Kafka.RetryInitialBackoff {
+		return fmt.Errorf("kafka retry backoff is invalid")
+	}
+	if c.Kafka.CommitInterval < 0 {
+		return fmt.Errorf("kafka commit interval must not be negative")
+	}
+	if c.Kafka.Workers < 1 {
+		return fmt.Errorf("kafka workers must be positive")
+	}
+	if c.Kafka.BatchSize < 1 || c.Kafka.BatchTimeout <= 0 {
+		return fmt.Errorf("kafka batch size and timeout must be positive")
+	}
 	if c.Server.Port == "" {
 		return fmt.Errorf("server port is missing")
 	}
+	if c.Server.NodeID == "" {
+		return fmt.Errorf("server node id is missing")
+	}
+	if c.Cache.TTL <= 0 {
+		return fmt.Errorf("cache ttl must be positive")
+	}
+	if c.Cache.MaxEntries < 0 || c.Cache.MaxBytes < 0 {
+		return fmt.Errorf("cache limits must not be negative")
+	}
+	if c.Cache.StaleGrace < 0 || c.Cache.NegativeTTL < 0 {
+		return fmt.Errorf("cache stale grace and negative ttl must not be negative")
+	}
+	for _, rule := range c.Cache.TTLByAge {
+		if rule.MaxAge <= 0 || rule.TTL <= 0 {
+			return fmt.Errorf("cache ttl by age rules must be positive")
+		}
+	}
+	if c.Cache.FinalTTL < 0 {
+		return fmt.Errorf("cache final ttl must not be negative")
+	}
+	switch c.Cache.Backend {
+	case "memory":
+	case "sharded":
+		if c.Cache.Shards < 1 {
+			return fmt.Errorf("cache shards must be positive")
+		}
+	case "redis":
+		if c.Cache.Redis.Addr == "" {
+			return fmt.Errorf("cache redis address is missing")
+		}
+		if c.Cache.Redis.Timeout <= 0 {
+			return fmt.Errorf("cache redis timeout must be positive")
+		}
+		if c.Cache.SnapshotPath != "" {
+			return fmt.Errorf("cache snapshots are not supported by the redis backend")
+		}
+	default:
+		return fmt.Errorf("unknown cache backend %q", c.Cache.Backend)
+	}
+	switch c.Warmup.Mode {
+	case "full", "none":
+	case "recent":
+		if c.Warmup.RecentDays < 1 {
+			return fmt.Errorf("warmup recent days must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown warmup mode %q", c.Warmup.Mode)
+	}
 	return nil
 }
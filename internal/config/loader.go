@@ -1,47 +1,260 @@
 package config
 
 import (
-    "log"
-    "os"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
-    "github.com/joho/godotenv"
+	"github.com/joho/godotenv"
 )
 
 func LoadConfig() (*Config, error) {
-    // Пытаемся загрузить .env файлы в порядке приоритета
-    envFiles := []string{
-        ".env",                    // Корень проекта (для Docker)
-        "internal/config/.env",    // Локальная конфигурация
-    }
-    
-    envLoaded := false
-    for _, envFile := range envFiles {
-        if err := godotenv.Load(envFile); err == nil {
-            log.Printf("Загружен конфиг из %s", envFile)
-            envLoaded = true
-            break
-        }
-    }
-    
-    if !envLoaded {
-        log.Println("No .env file found, using system environment variables")
-    }
-
-    cfg := &Config{
-        DB: DBConfig{
-            Host:     os.Getenv("DB_HOST"),
-            Port:     os.Getenv("DB_PORT"),
-            User:     os.Getenv("DB_USER"),
-            Password: os.Getenv("DB_PASSWORD"),
-            Name:     os.Getenv("DB_NAME"),
-            SSLMode:  os.Getenv("DB_SSLMODE"),
-        },
-        Kafka: KafkaConfig{
-            Broker: os.Getenv("KAFKA_BROKER"),
-        },
-        Server: ServerConfig{
-            Port: os.Getenv("SERVER_PORT"),
-        },
-    }
-    return cfg, nil
+	// Пытаемся загрузить .env файлы в порядке приоритета
+	envFiles := []string{
+		".env",                 // Корень проекта (для Docker)
+		"internal/config/.env", // Локальная конфигурация
+	}
+
+	envLoaded := false
+	for _, envFile := range envFiles {
+		if err := godotenv.Load(envFile); err == nil {
+			log.Printf("Загружен конфиг из %s", envFile)
+			envLoaded = true
+			break
+		}
+	}
+
+	if !envLoaded {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	retryMaxAttempts, err := getEnvInt("KAFKA_RETRY_MAX_ATTEMPTS", 5)
+	if err != nil {
+		return nil, err
+	}
+	retryInitialBackoff, err := getEnvDuration("KAFKA_RETRY_INITIAL_BACKOFF", 200*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	retryMaxBackoff, err := getEnvDuration("KAFKA_RETRY_MAX_BACKOFF", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	commitInterval, err := getEnvDuration("KAFKA_COMMIT_INTERVAL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	workers, err := getEnvInt("KAFKA_WORKERS", 4)
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := getEnvInt("KAFKA_BATCH_SIZE", 100)
+	if err != nil {
+		return nil, err
+	}
+	batchTimeout, err := getEnvDuration("KAFKA_BATCH_TIMEOUT", 50*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	cacheTTL, err := getEnvDuration("CACHE_TTL", 30*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cacheMaxEntries, err := getEnvInt("CACHE_MAX_ENTRIES", 0)
+	if err != nil {
+		return nil, err
+	}
+	cacheMaxBytes, err := getEnvInt("CACHE_MAX_BYTES", 0)
+	if err != nil {
+		return nil, err
+	}
+	cacheShards, err := getEnvInt("CACHE_SHARDS", 16)
+	if err != nil {
+		return nil, err
+	}
+	cacheStaleGrace, err := getEnvDuration("CACHE_STALE_GRACE", 0)
+	if err != nil {
+		return nil, err
+	}
+	redisDB, err := getEnvInt("CACHE_REDIS_DB", 0)
+	if err != nil {
+		return nil, err
+	}
+	redisTimeout, err := getEnvDuration("CACHE_REDIS_TIMEOUT", 500*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	warmupRecentDays, err := getEnvInt("WARMUP_RECENT_DAYS", 7)
+	if err != nil {
+		return nil, err
+	}
+	cacheNegativeTTL, err := getEnvDuration("CACHE_NEGATIVE_TTL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	cacheTTLByAge, err := getEnvAgeTTLs("CACHE_TTL_BY_AGE")
+	if err != nil {
+		return nil, err
+	}
+	cacheFinalStatuses, err := getEnvInts("CACHE_TTL_FINAL_STATUSES")
+	if err != nil {
+		return nil, err
+	}
+	cacheFinalTTL, err := getEnvDuration("CACHE_TTL_FINAL", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	groupID := getEnv("KAFKA_GROUP_ID", "group-1")
+
+	cfg := &Config{
+		DB: DBConfig{
+			Host:     os.Getenv("DB_HOST"),
+			Port:     os.Getenv("DB_PORT"),
+			User:     os.Getenv("DB_USER"),
+			Password: os.Getenv("DB_PASSWORD"),
+			Name:     os.Getenv("DB_NAME"),
+			SSLMode:  os.Getenv("DB_SSLMODE"),
+			Migrate:  getEnv("DB_MIGRATE", "check"),
+		},
+		Kafka: KafkaConfig{
+			Broker:            os.Getenv("KAFKA_BROKER"),
+			Topic:             getEnv("KAFKA_TOPIC", "orders"),
+			GroupID:           groupID,
+			DeadLetterTopic:   os.Getenv("KAFKA_DLQ_TOPIC"),
+			InvalidationTopic: os.Getenv("KAFKA_INVALIDATION_TOPIC"),
+			StatusTopic:       os.Getenv("KAFKA_STATUS_TOPIC"),
+			StatusGroupID:     getEnv("KAFKA_STATUS_GROUP_ID", groupID+"-status"),
+
+			RetryMaxAttempts:    retryMaxAttempts,
+			RetryInitialBackoff: retryInitialBackoff,
+			RetryMaxBackoff:     retryMaxBackoff,
+			CommitInterval:      commitInterval,
+			Workers:             workers,
+			BatchSize:           batchSize,
+			BatchTimeout:        batchTimeout,
+		},
+		Server: ServerConfig{
+			Port:   os.Getenv("SERVER_PORT"),
+			NodeID: getEnv("NODE_ID", defaultNodeID()),
+		},
+		Cache: CacheConfig{
+			Backend:        getEnv("CACHE_BACKEND", "memory"),
+			Shards:         cacheShards,
+			TTL:            cacheTTL,
+			MaxEntries:     cacheMaxEntries,
+			MaxBytes:       int64(cacheMaxBytes),
+			EvictionPolicy: getEnv("CACHE_EVICTION_POLICY", "lru"),
+			StaleGrace:     cacheStaleGrace,
+			NegativeTTL:    cacheNegativeTTL,
+			SnapshotPath:   os.Getenv("CACHE_SNAPSHOT_PATH"),
+			TTLByAge:       cacheTTLByAge,
+			FinalStatuses:  cacheFinalStatuses,
+			FinalTTL:       cacheFinalTTL,
+			EventLog:       getEnv("CACHE_EVENT_LOG", "false") == "true",
+			Redis: RedisConfig{
+				Addr:     getEnv("CACHE_REDIS_ADDR", "localhost:6379"),
+				Password: os.Getenv("CACHE_REDIS_PASSWORD"),
+				DB:       redisDB,
+				Prefix:   getEnv("CACHE_REDIS_PREFIX", "l0:"),
+				Codec:    getEnv("CACHE_REDIS_CODEC", "json"),
+				Timeout:  redisTimeout,
+			},
+		},
+		Warmup: WarmupConfig{
+			Mode:       getEnv("WARMUP_MODE", "full"),
+			RecentDays: warmupRecentDays,
+		},
+	}
+	return cfg, nil
+}
+
+// defaultNodeID — идентификатор реплики по умолчанию: имя хоста и PID процесса
+func defaultNodeID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "node"
+	}
+	return host + "-" + strconv.Itoa(os.Getpid())
+}
+
+// getEnv возвращает значение переменной окружения или значение по умолчанию
+func getEnv(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// getEnvInt возвращает целочисленное значение переменной окружения или значение по умолчанию
+func getEnvInt(key string, def int) (int, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// getEnvDuration возвращает длительность из переменной окружения (формат time.ParseDuration)
+func getEnvDuration(key string, def time.Duration) (time.Duration, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// getEnvInts разбирает список целых чисел через запятую, например "202,203"
+func getEnvInts(key string) ([]int, error) {
+	var values []int
+	for _, part := range strings.Split(getEnv(key, ""), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+// getEnvAgeTTLs разбирает правила вида "возраст=TTL" через запятую,
+// например "24h=2h,168h=30m": заказы не старше суток живут в кэше 2 часа,
+// не старше недели — 30 минут
+func getEnvAgeTTLs(key string) ([]AgeTTL, error) {
+	var rules []AgeTTL
+	for _, part := range strings.Split(getEnv(key, ""), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		age, ttl, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: expected age=ttl, got %q", key, part)
+		}
+		maxAge, err := time.ParseDuration(strings.TrimSpace(age))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(ttl))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		rules = append(rules, AgeTTL{MaxAge: maxAge, TTL: d})
+	}
+	return rules, nil
 }
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// sameOrder сравнивает содержимое двух заказов без учёта суррогатных ключей БД
+// и статуса, который меняется после создания заказа.
+// Время создания сравнивается с точностью PostgreSQL (микросекунды).
+func sameOrder(a, b *models.Order) bool {
+	return reflect.DeepEqual(normalizeOrder(a), normalizeOrder(b))
+}
+
+func normalizeOrder(o *models.Order) models.Order {
+	n := *o
+	n.Delivery.ID = 0
+	n.Payment.ID = 0
+	n.Status = ""
+	n.DateCreated = n.DateCreated.UTC().Truncate(time.Microsecond)
+
+	n.Items = make([]models.Item, len(o.Items))
+	for i, item := range o.Items {
+		item.ID = 0
+		item.OrderUID = ""
+		n.Items[i] = item
+	}
+	return n
+}
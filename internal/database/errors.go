@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgconn"
+)
+
+// IsTransient сообщает, является ли ошибка БД временной, то есть имеет ли
+// смысл повторить операцию позже: недоступность сервера, обрыв соединения,
+// конфликты сериализации и нехватка ресурсов. Ошибки данных и нарушения
+// ограничений считаются постоянными.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return isTransientSQLState(pgErr.Code)
+	}
+
+	if pgconn.Timeout(err) || pgconn.SafeToRetry(err) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isTransientSQLState — классы и коды SQLSTATE, после которых запрос можно повторить
+func isTransientSQLState(code string) bool {
+	switch code {
+	case "40001", // serialization_failure
+		"40P01", // deadlock_detected
+		"55P03", // lock_not_available
+		"57P01", // admin_shutdown
+		"57P02", // crash_shutdown
+		"57P03": // cannot_connect_now
+		return true
+	}
+	// 08 — connection exception, 53 — insufficient resources
+	return strings.HasPrefix(code, "08") || strings.HasPrefix(code, "53")
+}
+
+// ErrOrderConflict — заказ с таким order_uid уже сохранён с другим содержимым
+var ErrOrderConflict = errors.New("order already exists with different payload")
+
+// OrderConflictError — типизированная ошибка конфликта при повторной записи заказа.
+// Сопоставляется с ErrOrderConflict через errors.Is.
+type OrderConflictError struct {
+	OrderUID string
+}
+
+func (e *OrderConflictError) Error() string {
+	return "order " + e.OrderUID + ": " + ErrOrderConflict.Error()
+}
+
+func (e *OrderConflictError) Is(target error) bool {
+	return target == ErrOrderConflict
+}
+
+// ErrStatusChanged — статус заказа изменился между чтением и обновлением
+var ErrStatusChanged = errors.New("order status changed concurrently")
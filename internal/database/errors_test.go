@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/jackc/pgconn"
+)
+
+func TestIsTransient(t *testing.T) {
+	pg := func(code string) error {
+		return fmt.Errorf("query: %w", &pgconn.PgError{Code: code})
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", pg("40001"), true},
+		{"deadlock", pg("40P01"), true},
+		{"lock not available", pg("55P03"), true},
+		{"admin shutdown", pg("57P01"), true},
+		{"cannot connect now", pg("57P03"), true},
+		{"connection exception class", pg("08006"), true},
+		{"insufficient resources class", pg("53300"), true},
+		{"unique violation", pg("23505"), false},
+		{"foreign key violation", pg("23503"), false},
+		{"invalid text representation", pg("22P02"), false},
+		{"undefined column", pg("42703"), false},
+		{"context canceled", context.Canceled, false},
+		{"deadline exceeded", fmt.Errorf("exec: %w", context.DeadlineExceeded), true},
+		{"unexpected eof", io.ErrUnexpectedEOF, true},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"conflict", &OrderConflictError{OrderUID: "a"}, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Fatalf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
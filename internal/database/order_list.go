@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// defaultListLimit — размер страницы ListOrders, если он не задан
+const defaultListLimit = 50
+
+// ListOrders — страница заказов по фильтру, от новых к старым.
+// Пагинация по ключу (date_created, order_uid): следующая страница начинается
+// сразу после последнего заказа предыдущей, поэтому новые заказы не сдвигают
+// страницы. Фильтры по customer_id и track_number используют одноимённые индексы.
+func (r *OrderRepository) ListOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	sql, args := listOrdersQuery(filter, limit+1)
+	orders, err := selectOrders(ctx, r.db, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.OrderPage{Orders: orders}
+	if len(orders) > limit {
+		page.Orders = orders[:limit]
+		page.NextCursor = models.CursorOf(page.Orders[limit-1]).Encode()
+	}
+	if page.Orders == nil {
+		page.Orders = []models.Order{}
+	}
+	return page, nil
+}
+
+// listOrdersQuery строит запрос страницы заказов по фильтру
+func listOrdersQuery(filter models.OrderFilter, limit int) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	// add добавляет условие, заменяя каждый $? номером очередного аргумента
+	add := func(cond string, values ...interface{}) {
+		for _, v := range values {
+			args = append(args, v)
+			cond = strings.Replace(cond, "$?", "$"+strconv.Itoa(len(args)), 1)
+		}
+		conds = append(conds, cond)
+	}
+
+	if filter.CustomerID != "" {
+		add("o.customer_id = $?", filter.CustomerID)
+	}
+	if filter.TrackNumber != "" {
+		add("o.track_number = $?", filter.TrackNumber)
+	}
+	if filter.DeliveryService != "" {
+		add("o.delivery_service = $?", filter.DeliveryService)
+	}
+	if filter.PaymentProvider != "" {
+		add("p.provider = $?", filter.PaymentProvider)
+	}
+	if filter.Currency != "" {
+		add("p.currency = $?", filter.Currency)
+	}
+	if filter.Brand != "" {
+		add("EXISTS (SELECT 1 FROM items i WHERE i.order_uid = o.order_uid AND i.brand = $?)", filter.Brand)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		add("o.date_created >= $?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		add("o.date_created < $?", filter.CreatedTo)
+	}
+	if filter.After != nil {
+		add("(o.date_created, o.order_uid) < ($?, $?)", filter.After.DateCreated, filter.After.OrderUID)
+	}
+
+	sql := orderSelect
+	if len(conds) > 0 {
+		sql += "WHERE " + strings.Join(conds, " AND ") + "\n"
+	}
+	args = append(args, limit)
+	sql += "ORDER BY o.date_created DESC, o.order_uid DESC\nLIMIT $" + strconv.Itoa(len(args))
+	return sql, args
+}
+
+// GetOrdersByTrackNumber — все заказы с номером отслеживания (индекс idx_orders_track_number),
+// от новых к старым
+func (r *OrderRepository) GetOrdersByTrackNumber(ctx context.Context, trackNumber string) ([]models.Order, error) {
+	return selectOrders(ctx, r.db, orderSelect+`
+		WHERE o.track_number = $1
+		ORDER BY o.date_created DESC, o.order_uid DESC
+	`, trackNumber)
+}
+
+// GetOrdersByCustomer — все заказы покупателя (индекс idx_orders_customer_id), от новых к старым
+func (r *OrderRepository) GetOrdersByCustomer(ctx context.Context, customerID string) ([]models.Order, error) {
+	return selectOrders(ctx, r.db, orderSelect+`
+		WHERE o.customer_id = $1
+		ORDER BY o.date_created DESC, o.order_uid DESC
+	`, customerID)
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// streamPageSize — сколько заказов StreamOrders читает за один запрос
+const streamPageSize = 500
+
+// orderSelect — выборка заказа вместе с delivery и payment одним запросом.
+// LEFT JOIN и COALESCE позволяют прочитать заказ, даже если связанная
+// строка удалена (delivery_id/payment_id обнуляются через ON DELETE SET NULL).
+const orderSelect = `
+	SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id,
+	       o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status,
+	       COALESCE(d.id, 0), COALESCE(d.name, ''), COALESCE(d.phone, ''), COALESCE(d.zip, ''),
+	       COALESCE(d.city, ''), COALESCE(d.address, ''), COALESCE(d.region, ''), COALESCE(d.email, ''),
+	       COALESCE(p.id, 0), COALESCE(p.transaction, ''), COALESCE(p.request_id, ''), COALESCE(p.currency, ''),
+	       COALESCE(p.provider, ''), COALESCE(p.amount, 0), COALESCE(p.payment_dt, 0), COALESCE(p.bank, ''),
+	       COALESCE(p.delivery_cost, 0), COALESCE(p.goods_total, 0), COALESCE(p.custom_fee, 0)
+	FROM orders o
+	LEFT JOIN delivery d ON d.id = o.delivery_id
+	LEFT JOIN payment p ON p.id = o.payment_id
+`
+
+// selectOrders выполняет запрос, начинающийся с orderSelect, и догружает товары
+// всех найденных заказов одним запросом. Порядок заказов сохраняется.
+func selectOrders(ctx context.Context, q querier, sql string, args ...interface{}) ([]models.Order, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var o models.Order
+		if err := rows.Scan(&o.OrderUID, &o.TrackNumber, &o.Entry, &o.Locale, &o.InternalSignature,
+			&o.CustomerID, &o.DeliveryService, &o.Shardkey, &o.SmID, &o.DateCreated, &o.OofShard, &o.Status,
+			&o.Delivery.ID, &o.Delivery.Name, &o.Delivery.Phone, &o.Delivery.Zip,
+			&o.Delivery.City, &o.Delivery.Address, &o.Delivery.Region, &o.Delivery.Email,
+			&o.Payment.ID, &o.Payment.Transaction, &o.Payment.RequestID, &o.Payment.Currency,
+			&o.Payment.Provider, &o.Payment.Amount, &o.Payment.PaymentDt, &o.Payment.Bank,
+			&o.Payment.DeliveryCost, &o.Payment.GoodsTotal, &o.Payment.CustomFee); err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := attachItems(ctx, q, orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// attachItems загружает товары для набора заказов одним запросом
+func attachItems(ctx context.Context, q querier, orders []models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(orders))
+	uids := make([]string, len(orders))
+	for i, o := range orders {
+		index[o.OrderUID] = i
+		uids[i] = o.OrderUID
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid
+		FROM items WHERE order_uid = ANY($1)
+		ORDER BY order_uid, id
+	`, uids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.Item
+		var uid string
+		if err := rows.Scan(&item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale,
+			&item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status, &uid); err != nil {
+			return err
+		}
+		if i, ok := index[uid]; ok {
+			orders[i].Items = append(orders[i].Items, item)
+		}
+	}
+	return rows.Err()
+}
+
+// StreamOrders — потоковая выгрузка всех заказов.
+// Заказы читаются страницами по order_uid (keyset pagination), каждая страница —
+// двумя запросами; fn вызывается для каждого заказа, не дожидаясь загрузки остальных.
+// Ошибка, возвращённая fn, прерывает выгрузку.
+func (r *OrderRepository) StreamOrders(ctx context.Context, fn func(models.Order) error) error {
+	return r.streamOrders(ctx, `
+		WHERE o.order_uid > $1
+		ORDER BY o.order_uid
+		LIMIT $2
+	`, nil, fn)
+}
+
+// StreamOrdersSince — потоковая выгрузка заказов с date_created не раньше since.
+// Используется для прогрева кэша заказами за последние дни.
+func (r *OrderRepository) StreamOrdersSince(ctx context.Context, since time.Time, fn func(models.Order) error) error {
+	return r.streamOrders(ctx, `
+		WHERE o.order_uid > $1 AND o.date_created >= $3
+		ORDER BY o.order_uid
+		LIMIT $2
+	`, []interface{}{since}, fn)
+}
+
+// StreamOrdersUpdatedSince — потоковая выгрузка заказов, записанных или изменённых
+// не раньше since по часам сервера БД (updated_at). Используется для догрузки заказов
+// после снимка кэша: попадают и опоздавшие заказы, и смены статуса.
+func (r *OrderRepository) StreamOrdersUpdatedSince(ctx context.Context, since time.Time, fn func(models.Order) error) error {
+	return r.streamOrders(ctx, `
+		WHERE o.order_uid > $1 AND o.updated_at >= $3
+		ORDER BY o.order_uid
+		LIMIT $2
+	`, []interface{}{since}, fn)
+}
+
+// streamOrders читает страницы заказов запросом orderSelect+where, где $1 —
+// последний прочитанный order_uid, $2 — размер страницы, а extra передаются как $3...
+func (r *OrderRepository) streamOrders(ctx context.Context, where string, extra []interface{}, fn func(models.Order) error) error {
+	after := ""
+	for {
+		args := append([]interface{}{after, streamPageSize}, extra...)
+		page, err := selectOrders(ctx, r.db, orderSelect+where, args...)
+		if err != nil {
+			return err
+		}
+
+		for _, order := range page {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < streamPageSize {
+			return nil
+		}
+		after = page[len(page)-1].OrderUID
+	}
+}
@@ -2,8 +2,8 @@ package database
 
 import (
 	"context"
-	"errors"
 	"log"
+	"sort"
 
 	"github.com/highdolen/L0/internal/models"
 
@@ -11,6 +11,19 @@ import (
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+const (
+	insertOrderSQL = `
+		INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_id, locale,
+			internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, status)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+	`
+	// insertInitialStatusSQL — начальная запись истории статусов нового заказа
+	insertInitialStatusSQL = `
+		INSERT INTO order_status_history (order_uid, from_status, to_status, changed_at)
+		VALUES ($1, NULL, $2, $3)
+	`
+)
+
 type OrderRepository struct {
 	db *pgxpool.Pool
 }
@@ -19,7 +32,17 @@ func NewOrderRepository(db *pgxpool.Pool) *OrderRepository {
 	return &OrderRepository{db: db}
 }
 
-// CreateOrder — создание заказа с транзакцией
+// querier — общий интерфейс пула соединений и транзакции для чтения
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// CreateOrder — идемпотентное создание заказа с транзакцией.
+// Повторная запись заказа с тем же order_uid и идентичным содержимым ничего не меняет
+// и не считается ошибкой; если содержимое отличается, возвращается *OrderConflictError.
+// Новый заказ сохраняется в статусе created с начальной записью в истории статусов,
+// для уже сохранённого в order возвращается его текущий статус.
 func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
@@ -27,6 +50,27 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order)
 	}
 	defer tx.Rollback(ctx)
 
+	// Сериализуем конкурентную запись одного и того же заказа до конца транзакции
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, order.OrderUID); err != nil {
+		return err
+	}
+
+	// Проверяем, не сохранён ли заказ раньше, до вставки delivery и payment
+	existing, err := getOrderByUID(ctx, tx, order.OrderUID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if !sameOrder(existing, order) {
+			return &OrderConflictError{OrderUID: order.OrderUID}
+		}
+		order.Delivery.ID = existing.Delivery.ID
+		order.Payment.ID = existing.Payment.ID
+		order.Status = existing.Status
+		log.Printf("Заказ %s уже сохранён, повторная запись пропущена", order.OrderUID)
+		return nil
+	}
+
 	// Вставка Delivery
 	err = tx.QueryRow(ctx, `
 		INSERT INTO delivery (name, phone, zip, city, address, region, email)
@@ -51,17 +95,17 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order)
 	}
 
 	// Вставка Order
-	_, err = tx.Exec(ctx, `
-		INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_id, locale,
-			internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
-	`, order.OrderUID, order.TrackNumber, order.Entry, order.Delivery.ID, order.Payment.ID,
+	order.Status = models.StatusCreated
+	_, err = tx.Exec(ctx, insertOrderSQL, order.OrderUID, order.TrackNumber, order.Entry, order.Delivery.ID, order.Payment.ID,
 		order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService,
-		order.Shardkey, order.SmID, order.DateCreated, order.OofShard,
+		order.Shardkey, order.SmID, order.DateCreated, order.OofShard, order.Status,
 	)
 	if err != nil {
 		return err
 	}
+	if _, err := tx.Exec(ctx, insertInitialStatusSQL, order.OrderUID, order.Status, order.DateCreated); err != nil {
+		return err
+	}
 
 	// Вставка Items
 	for _, item := range order.Items {
@@ -79,96 +123,193 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order)
 	return tx.Commit(ctx)
 }
 
-// GetOrderByUID — получение заказа по UID
-// GetOrderByUID — получение заказа по UID
-func (r *OrderRepository) GetOrderByUID(ctx context.Context, uid string) (*models.Order, error) {
-	var order models.Order
+// CreateOrders — пакетное идемпотентное создание заказов в одной транзакции.
+// Delivery и payment вставляются одним pgx.Batch, заказы — вторым, товары — через COPY.
+// Уже сохранённые заказы с идентичным содержимым пропускаются; при расхождении
+// содержимого возвращается *OrderConflictError и транзакция откатывается целиком.
+// Статусы новых и уже сохранённых заказов заполняются так же, как в CreateOrder.
+func (r *OrderRepository) CreateOrders(ctx context.Context, orders []models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
 
-	err := r.db.QueryRow(ctx, `
-        SELECT order_uid, track_number, entry, locale, internal_signature, customer_id,
-               delivery_service, shardkey, sm_id, date_created, oof_shard, delivery_id, payment_id
-        FROM orders WHERE order_uid = $1
-    `, uid).Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
-		&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated,
-		&order.OofShard, &order.Delivery.ID, &order.Payment.ID)
+	// Повторы одного заказа внутри пакета: одинаковые пропускаем, разные — конфликт
+	first := make(map[string]int, len(orders))
+	uids := make([]string, 0, len(orders))
+	for i := range orders {
+		uid := orders[i].OrderUID
+		if j, ok := first[uid]; ok {
+			if !sameOrder(&orders[j], &orders[i]) {
+				return &OrderConflictError{OrderUID: uid}
+			}
+			continue
+		}
+		first[uid] = i
+		uids = append(uids, uid)
+	}
 
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		log.Printf("[DEBUG] Query orders error for uid=%s: %v", uid, err)
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
+		return err
 	}
+	defer tx.Rollback(ctx)
 
-	log.Printf("[DEBUG] Found order: %s", order.OrderUID)
+	// Блокировки берём в едином порядке, чтобы параллельные пакеты не взаимоблокировались
+	sorted := append([]string(nil), uids...)
+	sort.Strings(sorted)
+	locks := &pgx.Batch{}
+	for _, uid := range sorted {
+		locks.Queue(`SELECT pg_advisory_xact_lock(hashtext($1))`, uid)
+	}
+	if err := tx.SendBatch(ctx, locks).Close(); err != nil {
+		return err
+	}
 
-	// Delivery
-	err = r.db.QueryRow(ctx, `
-		SELECT name, phone, zip, city, address, region, email FROM delivery WHERE id = $1
-	`, order.Delivery.ID).Scan(&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip,
-		&order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email)
+	// Отсекаем заказы, которые уже сохранены
+	existing, err := existingOrderUIDs(ctx, tx, uids)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	fresh := make([]*models.Order, 0, len(uids))
+	for _, uid := range uids {
+		order := &orders[first[uid]]
+		if !existing[uid] {
+			fresh = append(fresh, order)
+			continue
+		}
+		stored, err := getOrderByUID(ctx, tx, uid)
+		if err != nil {
+			return err
+		}
+		if stored == nil || !sameOrder(stored, order) {
+			return &OrderConflictError{OrderUID: uid}
+		}
+		order.Delivery.ID = stored.Delivery.ID
+		order.Payment.ID = stored.Payment.ID
+		order.Status = stored.Status
+	}
+	if len(fresh) == 0 {
+		return tx.Commit(ctx)
 	}
 
-	// Payment
-	err = r.db.QueryRow(ctx, `
-		SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
-		FROM payment WHERE id = $1
-	`, order.Payment.ID).Scan(&order.Payment.Transaction, &order.Payment.RequestID, &order.Payment.Currency,
-		&order.Payment.Provider, &order.Payment.Amount, &order.Payment.PaymentDt, &order.Payment.Bank,
-		&order.Payment.DeliveryCost, &order.Payment.GoodsTotal, &order.Payment.CustomFee)
-	if err != nil {
-		return nil, err
+	// Delivery и Payment — получаем сгенерированные id
+	parents := &pgx.Batch{}
+	for _, order := range fresh {
+		parents.Queue(`
+			INSERT INTO delivery (name, phone, zip, city, address, region, email)
+			VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id
+		`, order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City,
+			order.Delivery.Address, order.Delivery.Region, order.Delivery.Email)
+		parents.Queue(`
+			INSERT INTO payment (transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING id
+		`, order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency,
+			order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDt,
+			order.Payment.Bank, order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee)
+	}
+	results := tx.SendBatch(ctx, parents)
+	for _, order := range fresh {
+		if err := results.QueryRow().Scan(&order.Delivery.ID); err != nil {
+			results.Close()
+			return err
+		}
+		if err := results.QueryRow().Scan(&order.Payment.ID); err != nil {
+			results.Close()
+			return err
+		}
+	}
+	if err := results.Close(); err != nil {
+		return err
 	}
 
-	// Items
-	rows, err := r.db.Query(ctx, `
-		SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
-		FROM items WHERE order_uid = $1
-	`, uid)
-	if err != nil {
-		return nil, err
+	// Orders и начальные записи истории статусов
+	rows := &pgx.Batch{}
+	for _, order := range fresh {
+		order.Status = models.StatusCreated
+		rows.Queue(insertOrderSQL, order.OrderUID, order.TrackNumber, order.Entry, order.Delivery.ID, order.Payment.ID,
+			order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService,
+			order.Shardkey, order.SmID, order.DateCreated, order.OofShard, order.Status)
+		rows.Queue(insertInitialStatusSQL, order.OrderUID, order.Status, order.DateCreated)
+	}
+	if err := tx.SendBatch(ctx, rows).Close(); err != nil {
+		return err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var item models.Item
-		if err := rows.Scan(&item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale,
-			&item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status); err != nil {
-			return nil, err
+	// Items
+	var items [][]interface{}
+	for _, order := range fresh {
+		for _, item := range order.Items {
+			items = append(items, []interface{}{
+				item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale,
+				item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status, order.OrderUID,
+			})
 		}
-		order.Items = append(order.Items, item)
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"items"}, []string{
+		"chrt_id", "track_number", "price", "rid", "name", "sale",
+		"size", "total_price", "nm_id", "brand", "status", "order_uid",
+	}, pgx.CopyFromRows(items))
+	if err != nil {
+		return err
 	}
 
-	return &order, nil
+	return tx.Commit(ctx)
 }
 
-// GetAllOrders — получить все заказы
-func (r *OrderRepository) GetAllOrders(ctx context.Context) ([]models.Order, error) {
-	rows, err := r.db.Query(ctx, `
-		SELECT order_uid FROM orders
-	`)
+// existingOrderUIDs — какие из переданных order_uid уже есть в таблице orders
+func existingOrderUIDs(ctx context.Context, q querier, uids []string) (map[string]bool, error) {
+	rows, err := q.Query(ctx, `SELECT order_uid FROM orders WHERE order_uid = ANY($1)`, uids)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var orders []models.Order
+	existing := make(map[string]bool)
 	for rows.Next() {
 		var uid string
 		if err := rows.Scan(&uid); err != nil {
 			return nil, err
 		}
+		existing[uid] = true
+	}
+	return existing, rows.Err()
+}
 
-		order, err := r.GetOrderByUID(ctx, uid)
-		if err != nil {
-			return nil, err
-		}
-		if order != nil {
-			orders = append(orders, *order)
-		}
+// GetOrderByUID — получение заказа по UID
+func (r *OrderRepository) GetOrderByUID(ctx context.Context, uid string) (*models.Order, error) {
+	order, err := getOrderByUID(ctx, r.db, uid)
+	if err != nil {
+		log.Printf("[DEBUG] Query orders error for uid=%s: %v", uid, err)
+		return nil, err
+	}
+	if order != nil {
+		log.Printf("[DEBUG] Found order: %s", order.OrderUID)
+	}
+	return order, nil
+}
+
+// getOrderByUID — получение заказа по UID через пул или внутри транзакции
+func getOrderByUID(ctx context.Context, q querier, uid string) (*models.Order, error) {
+	orders, err := selectOrders(ctx, q, orderSelect+`WHERE o.order_uid = $1`, uid)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, nil
 	}
+	return &orders[0], nil
+}
 
+// GetAllOrders — получить все заказы.
+// Для больших таблиц предпочтительнее StreamOrders, не держащий все заказы в памяти.
+func (r *OrderRepository) GetAllOrders(ctx context.Context) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.StreamOrders(ctx, func(order models.Order) error {
+		orders = append(orders, order)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return orders, nil
 }
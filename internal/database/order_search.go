@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// searchQuery ранжирует заказы по совпадениям в данных доставки и товарах
+// (GIN-индексы idx_delivery_search и idx_items_search) и строит фрагменты
+// с подсветкой только для возвращаемых заказов. $1 — запрос в синтаксисе
+// websearch_to_tsquery ("точная фраза", -исключение, or), $2 — число заказов.
+const searchQuery = `
+	WITH q AS (
+		SELECT websearch_to_tsquery('simple', $1) AS query
+	),
+	ranked AS (
+		SELECT m.order_uid, sum(m.rank) AS rank
+		FROM (
+			SELECT o.order_uid, ts_rank(d.search_vector, q.query) AS rank
+			FROM q, delivery d
+			JOIN orders o ON o.delivery_id = d.id
+			WHERE d.search_vector @@ q.query
+			UNION ALL
+			SELECT i.order_uid, ts_rank(i.search_vector, q.query)
+			FROM q, items i
+			WHERE i.search_vector @@ q.query
+		) m
+		GROUP BY m.order_uid
+		ORDER BY rank DESC, m.order_uid
+		LIMIT $2
+	)
+	SELECT r.order_uid, r.rank,
+	       ts_headline('simple',
+	           concat_ws(' · ', d.name, d.city, d.address, d.email,
+	               (SELECT string_agg(concat_ws(' ', i.name, i.brand), ' · ' ORDER BY i.id)
+	                FROM items i WHERE i.order_uid = r.order_uid)),
+	           q.query,
+	           'StartSel=<mark>, StopSel=</mark>, MaxFragments=3, MinWords=3, MaxWords=15, FragmentDelimiter=" … "')
+	FROM ranked r
+	CROSS JOIN q
+	JOIN orders o ON o.order_uid = r.order_uid
+	LEFT JOIN delivery d ON d.id = o.delivery_id
+	ORDER BY r.rank DESC, r.order_uid
+`
+
+// SearchOrders — полнотекстовый поиск заказов по имени, городу, адресу и email
+// получателя, названиям и брендам товаров. Возвращает не более limit заказов
+// по убыванию релевантности.
+func (r *OrderRepository) SearchOrders(ctx context.Context, query string, limit int) ([]models.OrderSearchResult, error) {
+	rows, err := r.db.Query(ctx, searchQuery, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.OrderSearchResult{}
+	var uids []string
+	for rows.Next() {
+		var res models.OrderSearchResult
+		var rank float32
+		if err := rows.Scan(&res.Order.OrderUID, &rank, &res.Highlight); err != nil {
+			return nil, err
+		}
+		res.Rank = float64(rank)
+		results = append(results, res)
+		uids = append(uids, res.Order.OrderUID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if len(uids) == 0 {
+		return results, nil
+	}
+	orders, err := selectOrders(ctx, r.db, orderSelect+`WHERE o.order_uid = ANY($1)`, uids)
+	if err != nil {
+		return nil, err
+	}
+	byUID := make(map[string]models.Order, len(orders))
+	for _, o := range orders {
+		byUID[o.OrderUID] = o
+	}
+	// Заказ мог быть удалён между запросами — такие результаты пропускаем
+	found := results[:0]
+	for _, res := range results {
+		if order, ok := byUID[res.Order.OrderUID]; ok {
+			res.Order = order
+			found = append(found, res)
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+
+	"github.com/highdolen/L0/internal/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// GetOrderStatus — текущий статус заказа; found == false, если заказа нет
+func (r *OrderRepository) GetOrderStatus(ctx context.Context, uid string) (models.OrderStatus, bool, error) {
+	var status models.OrderStatus
+	err := r.db.QueryRow(ctx, `SELECT status FROM orders WHERE order_uid = $1`, uid).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return status, true, nil
+}
+
+// UpdateOrderStatus меняет статус заказа с from на change.Status и добавляет запись
+// в историю одной транзакцией. Обновление выполняется, только если текущий статус
+// всё ещё from; иначе возвращается ErrStatusChanged и ничего не меняется.
+// Допустимость перехода проверяет сервисный слой.
+func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, from models.OrderStatus, change models.StatusChange) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE orders SET status = $3, updated_at = now() WHERE order_uid = $1 AND status = $2`,
+		change.OrderUID, from, change.Status)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrStatusChanged
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO order_status_history (order_uid, from_status, to_status, changed_at, reason)
+		VALUES ($1, $2, $3, $4, $5)
+	`, change.OrderUID, from, change.Status, change.ChangedAt, change.Reason)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetStatusHistory — история статусов заказа в порядке применения переходов.
+// Пустой список означает, что заказа нет.
+func (r *OrderRepository) GetStatusHistory(ctx context.Context, uid string) ([]models.StatusHistoryEntry, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT COALESCE(from_status, ''), to_status, changed_at, reason
+		FROM order_status_history
+		WHERE order_uid = $1
+		ORDER BY id
+	`, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.StatusHistoryEntry{}
+	for rows.Next() {
+		var e models.StatusHistoryEntry
+		if err := rows.Scan(&e.From, &e.To, &e.ChangedAt, &e.Reason); err != nil {
+			return nil, err
+		}
+		history = append(history, e)
+	}
+	return history, rows.Err()
+}
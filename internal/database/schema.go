@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ErrSchemaOutdated — в БД нет таблиц или столбцов, с которыми работает репозиторий
+var ErrSchemaOutdated = errors.New("схема БД не соответствует версии сервиса")
+
+// requiredSchema — запросы, которые завершаются ошибкой, если схема старее кода:
+// векторы полнотекстового поиска (миграция 0003), статус заказа и история
+// статусов (0004), время изменения заказа (0005)
+var requiredSchema = []struct {
+	migration string
+	query     string
+}{
+	{"0003_search_vectors", `SELECT search_vector FROM delivery LIMIT 0`},
+	{"0003_search_vectors", `SELECT search_vector FROM items LIMIT 0`},
+	{"0004_order_status", `SELECT status FROM orders LIMIT 0`},
+	{"0004_order_status", `SELECT order_uid, from_status, to_status, changed_at, reason FROM order_status_history LIMIT 0`},
+	{"0005_orders_updated_at", `SELECT updated_at FROM orders LIMIT 0`},
+}
+
+// CheckSchema проверяет, что в БД есть всё, что использует репозиторий.
+// Проверка не зависит от таблицы schema_migrations, поэтому работает и для
+// схемы, которую обновляют вручную (DB_MIGRATE=off).
+func CheckSchema(ctx context.Context, db *pgxpool.Pool) error {
+	for _, r := range requiredSchema {
+		if _, err := db.Exec(ctx, r.query); err != nil {
+			return fmt.Errorf("%w: нужна миграция %s: %v", ErrSchemaOutdated, r.migration, err)
+		}
+	}
+	return nil
+}
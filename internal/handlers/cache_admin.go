@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// defaultKeysLimit — размер страницы /cache/keys по умолчанию
+	defaultKeysLimit = 100
+	// maxKeysLimit — наибольший допустимый размер страницы /cache/keys
+	maxKeysLimit = 1000
+)
+
+// cacheKeysResponse — страница UID заказов в кеше
+type cacheKeysResponse struct {
+	Keys []string `json:"keys"`
+	// Next — значение параметра after для следующей страницы; пусто, если страниц больше нет
+	Next string `json:"next,omitempty"`
+}
+
+// bulkInvalidateRequest — тело запроса массовой инвалидации
+type bulkInvalidateRequest struct {
+	OrderUIDs  []string `json:"order_uids"`
+	CustomerID string   `json:"customer_id"`
+}
+
+// ListCacheKeys — список UID заказов в кеше.
+// Параметры: prefix — фильтр по префиксу UID, after — курсор (последний UID
+// предыдущей страницы), limit — размер страницы (по умолчанию 100, не больше 1000).
+func (h *OrderHandler) ListCacheKeys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := defaultKeysLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxKeysLimit)
+	}
+
+	keys, next := h.orderService.ListCacheKeys(query.Get("prefix"), query.Get("after"), limit)
+	if keys == nil {
+		keys = []string{}
+	}
+	writeJSON(w, cacheKeysResponse{Keys: keys, Next: next}, http.StatusOK)
+}
+
+// GetCacheEntry — сведения о записи заказа в кеше: время добавления,
+// оставшийся TTL и приблизительный размер
+func (h *OrderHandler) GetCacheEntry(w http.ResponseWriter, r *http.Request) {
+	uid := mux.Vars(r)["order_uid"]
+
+	entry, ok := h.orderService.GetCacheEntry(uid)
+	if !ok {
+		http.Error(w, "Заказа нет в кеше", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, entry, http.StatusOK)
+}
+
+// BulkInvalidateCache — инвалидировать заказы по списку UID и/или все
+// закешированные заказы покупателя. Маршрут регистрируется без ограничения
+// методов, чтобы DELETE /cache/invalidate/bulk не попал в инвалидацию заказа
+// с UID "bulk", поэтому метод проверяется здесь.
+func (h *OrderHandler) BulkInvalidateCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Метод не поддерживается, используйте POST", http.StatusMethodNotAllowed)
+		return
+	}
+	var req bulkInvalidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Некорректное тело запроса: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.OrderUIDs) == 0 && req.CustomerID == "" {
+		http.Error(w, "Нужно указать order_uids или customer_id", http.StatusBadRequest)
+		return
+	}
+
+	invalidated := h.orderService.InvalidateCacheBulk(req.OrderUIDs, req.CustomerID)
+	writeJSON(w, map[string]interface{}{
+		"invalidated": len(invalidated),
+		"order_uids":  invalidated,
+	}, http.StatusOK)
+}
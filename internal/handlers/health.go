@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/highdolen/L0/internal/kafka"
+)
+
+// StallReporter — consumer, сообщающий о сообщениях, которые он не может завершить
+type StallReporter interface {
+	Stalls() []kafka.Stall
+}
+
+// ConsumersHealth — состояние обработки сообщений Kafka
+type ConsumersHealth struct {
+	Status string `json:"status"` // ok или stalled
+	// Consumers — застрявшие сообщения по имени consumer'а
+	Consumers map[string][]kafka.Stall `json:"consumers"`
+}
+
+// WatchConsumer добавляет consumer в проверку /health
+func (h *OrderHandler) WatchConsumer(name string, c StallReporter) {
+	if h.consumers == nil {
+		h.consumers = make(map[string]StallReporter)
+	}
+	h.consumers[name] = c
+}
+
+// Health — проверка обработки сообщений: 200, пока все consumer'ы коммитят offset'ы,
+// иначе 503 со списком сообщений, на которых остановился коммит их партиций
+func (h *OrderHandler) Health(w http.ResponseWriter, r *http.Request) {
+	health := ConsumersHealth{Status: "ok", Consumers: make(map[string][]kafka.Stall, len(h.consumers))}
+	for name, c := range h.consumers {
+		stalls := c.Stalls()
+		if len(stalls) > 0 {
+			health.Status = "stalled"
+		}
+		health.Consumers[name] = stalls
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if health.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		http.Error(w, "Ошибка при кодировании ответа", http.StatusInternalServerError)
+	}
+}
+
+// Ready — readiness-проверка: 200, когда прогрев кэша завершён, иначе 503.
+// В теле ответа — состояние и прогресс прогрева.
+func (h *OrderHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	status := h.orderService.WarmupStatus()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		http.Error(w, "Ошибка при кодировании ответа", http.StatusInternalServerError)
+	}
+}
+
+// Live — liveness-проверка: процесс запущен и обслуживает HTTP
+func (h *OrderHandler) Live(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
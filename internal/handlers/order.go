@@ -6,13 +6,32 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
+	"github.com/highdolen/L0/internal/models"
 	"github.com/highdolen/L0/internal/service"
 )
 
+const (
+	// defaultOrdersLimit — размер страницы /orders по умолчанию
+	defaultOrdersLimit = 50
+	// maxOrdersLimit — наибольший допустимый размер страницы /orders
+	maxOrdersLimit = 500
+
+	// defaultSearchLimit и maxSearchLimit — число результатов /orders/search
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// maxSearchQueryLen — наибольшая длина поискового запроса в символах
+	maxSearchQueryLen = 200
+)
+
 type OrderHandler struct {
 	orderService service.OrderService
+	consumers    map[string]StallReporter
 }
 
 func NewOrderHandler(orderService service.OrderService) *OrderHandler {
@@ -41,7 +60,7 @@ func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		// Проверяем, является ли ошибка "заказ не найден"
-		if errors.Is(err, errors.New("заказ не найден")) {
+		if errors.Is(err, service.ErrOrderNotFound) {
 			http.Error(w, "Заказ не найден", http.StatusNotFound)
 		} else {
 			http.Error(w, "Ошибка при получении заказа: "+err.Error(), http.StatusInternalServerError)
@@ -51,7 +70,9 @@ func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 
 	// Устанавливаем заголовки ответа
 	w.Header().Set("Content-Type", "application/json")
-	if result.FromCache {
+	if result.Stale {
+		w.Header().Set("X-Cache", "STALE")
+	} else if result.FromCache {
 		w.Header().Set("X-Cache", "HIT")
 	} else {
 		w.Header().Set("X-Cache", "MISS")
@@ -63,6 +84,167 @@ func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// SearchOrders — полнотекстовый поиск заказов по имени, городу, адресу и email
+// получателя, названиям и брендам товаров. Параметры: q — запрос (поддерживаются
+// "фразы", -исключения и or), limit — число результатов (по умолчанию 20, не больше 100).
+func (h *OrderHandler) SearchOrders(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Не указан поисковый запрос q", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLen {
+		http.Error(w, "Слишком длинный поисковый запрос", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	results, err := h.orderService.SearchOrders(r.Context(), query, limit)
+	if err != nil {
+		http.Error(w, "Ошибка поиска заказов: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"results": results}, http.StatusOK)
+}
+
+// GetOrdersByTrackNumber — заказы по номеру отслеживания, от новых к старым
+func (h *OrderHandler) GetOrdersByTrackNumber(w http.ResponseWriter, r *http.Request) {
+	trackNumber := mux.Vars(r)["track_number"]
+
+	result, err := h.orderService.GetOrdersByTrackNumber(r.Context(), trackNumber)
+	if err != nil {
+		if errors.Is(err, service.ErrOrderNotFound) {
+			http.Error(w, "Заказ не найден", http.StatusNotFound)
+		} else {
+			http.Error(w, "Ошибка при получении заказов: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	writeOrders(w, result)
+}
+
+// GetOrderHistory — история статусов заказа в порядке изменений вместе с текущим статусом
+func (h *OrderHandler) GetOrderHistory(w http.ResponseWriter, r *http.Request) {
+	uid := mux.Vars(r)["order_uid"]
+
+	history, err := h.orderService.GetOrderStatusHistory(r.Context(), uid)
+	if err != nil {
+		if errors.Is(err, service.ErrOrderNotFound) {
+			http.Error(w, "Заказ не найден", http.StatusNotFound)
+		} else {
+			http.Error(w, "Ошибка при получении истории статусов: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"order_uid": uid,
+		"status":    history[len(history)-1].To,
+		"history":   history,
+	}, http.StatusOK)
+}
+
+// GetCustomerOrders — все заказы покупателя, от новых к старым
+func (h *OrderHandler) GetCustomerOrders(w http.ResponseWriter, r *http.Request) {
+	customerID := mux.Vars(r)["customer_id"]
+
+	result, err := h.orderService.GetOrdersByCustomer(r.Context(), customerID)
+	if err != nil {
+		http.Error(w, "Ошибка при получении заказов: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeOrders(w, result)
+}
+
+// writeOrders отдаёт найденные заказы с заголовком X-Cache
+func writeOrders(w http.ResponseWriter, result *service.OrdersResult) {
+	if result.FromCache {
+		w.Header().Set("X-Cache", "HIT")
+	} else {
+		w.Header().Set("X-Cache", "MISS")
+	}
+	writeJSON(w, map[string]interface{}{"orders": result.Orders}, http.StatusOK)
+}
+
+// ListOrders — список заказов с фильтрами и пагинацией по курсору.
+// Параметры: customer_id, track_number, delivery_service, provider, currency, brand,
+// created_from и created_to (RFC3339 или дата YYYY-MM-DD; дата в created_to включается
+// целиком), cursor — next_cursor предыдущей страницы, limit — размер страницы
+// (по умолчанию 50, не больше 500).
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.OrderFilter{
+		CustomerID:      query.Get("customer_id"),
+		TrackNumber:     query.Get("track_number"),
+		DeliveryService: query.Get("delivery_service"),
+		PaymentProvider: query.Get("provider"),
+		Currency:        query.Get("currency"),
+		Brand:           query.Get("brand"),
+		Limit:           defaultOrdersLimit,
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseTimeParam(query.Get("created_from"), false); err != nil {
+		http.Error(w, "Некорректный параметр created_from", http.StatusBadRequest)
+		return
+	}
+	if filter.CreatedTo, err = parseTimeParam(query.Get("created_to"), true); err != nil {
+		http.Error(w, "Некорректный параметр created_to", http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = min(n, maxOrdersLimit)
+	}
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := models.ParseOrderCursor(v)
+		if err != nil {
+			http.Error(w, "Некорректный параметр cursor", http.StatusBadRequest)
+			return
+		}
+		filter.After = &cursor
+	}
+
+	page, err := h.orderService.ListOrders(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Ошибка при получении списка заказов: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, page, http.StatusOK)
+}
+
+// parseTimeParam разбирает время в формате RFC3339 или дату YYYY-MM-DD.
+// Для даты с endOfDay возвращается начало следующего дня, чтобы граница
+// "меньше created_to" включала указанный день целиком.
+func parseTimeParam(v string, endOfDay bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
 // GetCacheStats — получить статистику кеша
 func (h *OrderHandler) GetCacheStats(w http.ResponseWriter, r *http.Request) {
 	stats := h.orderService.GetCacheStats()
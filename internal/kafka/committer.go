@@ -0,0 +1,124 @@
+package kafka
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// topicPartition — ключ партиции для учёта offset'ов
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// committer накапливает обработанные сообщения и периодически коммитит
+// последний offset каждой партиции одним запросом
+type committer struct {
+	reader   *kafka.Reader
+	interval time.Duration
+
+	mu        sync.Mutex
+	pending   map[topicPartition]kafka.Message
+	committed map[topicPartition]int64 // последний закоммиченный offset партиции
+}
+
+func newCommitter(reader *kafka.Reader, interval time.Duration) *committer {
+	return &committer{
+		reader:    reader,
+		interval:  interval,
+		pending:   make(map[topicPartition]kafka.Message),
+		committed: make(map[topicPartition]int64),
+	}
+}
+
+// MarkDone — отметить сообщение как обработанное (сохранённое или отправленное в DLQ).
+// При нулевом интервале offset коммитится сразу.
+func (c *committer) MarkDone(m kafka.Message) {
+	key := topicPartition{topic: m.Topic, partition: m.Partition}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Обработчики работают параллельно, поэтому более старый offset
+	// может прийти после нового — его коммит откатил бы партицию назад
+	if last, ok := c.committed[key]; ok && m.Offset <= last {
+		return
+	}
+
+	if c.interval <= 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := c.reader.CommitMessages(ctx, m); err != nil {
+			log.Printf("Ошибка коммита offset %s/%d@%d: %v", m.Topic, m.Partition, m.Offset, err)
+			return
+		}
+		c.committed[key] = m.Offset
+		return
+	}
+
+	if prev, ok := c.pending[key]; !ok || m.Offset > prev.Offset {
+		c.pending[key] = m
+	}
+}
+
+// Flush — закоммитить все накопленные offset'ы.
+// Если коммит не удался, offset'ы остаются в очереди до следующей попытки.
+func (c *committer) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	if len(c.pending) == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	msgs := make([]kafka.Message, 0, len(c.pending))
+	for _, m := range c.pending {
+		msgs = append(msgs, m)
+	}
+	c.pending = make(map[topicPartition]kafka.Message)
+	c.mu.Unlock()
+
+	if err := c.reader.CommitMessages(ctx, msgs...); err != nil {
+		// Возвращаем offset'ы обратно, если за это время не появились более новые
+		for _, m := range msgs {
+			c.MarkDone(m)
+		}
+		return err
+	}
+
+	c.mu.Lock()
+	for _, m := range msgs {
+		key := topicPartition{topic: m.Topic, partition: m.Partition}
+		if last, ok := c.committed[key]; !ok || m.Offset > last {
+			c.committed[key] = m.Offset
+		}
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// run периодически коммитит накопленные offset'ы до отмены контекста.
+// Финальный коммит при остановке выполняется через Flush.
+func (c *committer) run(ctx context.Context) {
+	if c.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			flushCtx, cancel := context.WithTimeout(context.Background(), c.interval)
+			if err := c.Flush(flushCtx); err != nil {
+				log.Printf("Ошибка периодического коммита offset'ов: %v", err)
+			}
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -3,46 +3,132 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"hash/fnv"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/highdolen/L0/internal/cache"
 	"github.com/highdolen/L0/internal/database"
 	"github.com/highdolen/L0/internal/models"
 	"github.com/segmentio/kafka-go"
 )
 
+// ConsumerConfig — параметры подключения consumer'а к Kafka
+type ConsumerConfig struct {
+	Brokers []string
+	Topic   string
+	GroupID string
+	// DeadLetterTopic — топик для сообщений, которые не удалось обработать.
+	// Если пустой, сообщения с детерминированной ошибкой только логируются, а
+	// сохранение остальных повторяется до успеха (см. processUntilCommittable).
+	DeadLetterTopic string
+	// Retry — повторы сохранения в БД при временных ошибках
+	Retry RetryPolicy
+	// CommitInterval — период пакетного коммита offset'ов.
+	// Нулевое значение — синхронный коммит после каждого сообщения.
+	CommitInterval time.Duration
+	// Workers — число параллельных обработчиков. Сообщения с одинаковым ключом
+	// (order_uid) всегда попадают к одному обработчику и обрабатываются по порядку.
+	Workers int
+	// BatchSize — максимальный размер пакета заказов, сохраняемых одной транзакцией.
+	// Значение 1 отключает пакетную запись.
+	BatchSize int
+	// BatchTimeout — сколько обработчик ждёт заполнения пакета перед записью
+	BatchTimeout time.Duration
+}
+
+// OrderCache — кэш, в который consumer кладёт сохранённые заказы
+type OrderCache interface {
+	Set(uid string, order models.Order)
+}
+
 type Consumer struct {
-	reader   *kafka.Reader
-	repo     *database.OrderRepository
-	cache    *cache.OrderCache
-	validate *validator.Validate
+	reader     *kafka.Reader
+	committer  *committer
+	offsets    *offsetTracker
+	workers    int
+	batchSize  int
+	batchWait  time.Duration
+	done       chan struct{}
+	deadLetter *DeadLetterPublisher
+	stalls     *stallTracker
+	retry      RetryPolicy
+	repo       *database.OrderRepository
+	cache      OrderCache
+	validate   *validator.Validate
 }
 
-func NewConsumer(brokers []string, topic, groupID string, repo *database.OrderRepository, cache *cache.OrderCache) *Consumer {
+func NewConsumer(cfg ConsumerConfig, repo *database.OrderRepository, cache OrderCache) *Consumer {
 	r := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:     brokers,
-		Topic:       topic,
-		GroupID:     groupID,
+		Brokers:     cfg.Brokers,
+		Topic:       cfg.Topic,
+		GroupID:     cfg.GroupID,
 		StartOffset: kafka.LastOffset,
 		MinBytes:    10e3, // 10KB
 		MaxBytes:    10e6, // 10MB
 		MaxWait:     time.Second,
 	})
 
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize < 1 || cfg.BatchTimeout <= 0 {
+		batchSize = 1
+	}
+
+	var dlq *DeadLetterPublisher
+	if cfg.DeadLetterTopic != "" {
+		dlq = NewDeadLetterPublisher(cfg.Brokers, cfg.DeadLetterTopic)
+	}
+
 	return &Consumer{
-		reader:   r,
-		repo:     repo,
-		cache:    cache,
-		validate: validator.New(),
+		reader:     r,
+		committer:  newCommitter(r, cfg.CommitInterval),
+		offsets:    newOffsetTracker(),
+		workers:    workers,
+		batchSize:  batchSize,
+		batchWait:  cfg.BatchTimeout,
+		done:       make(chan struct{}),
+		deadLetter: dlq,
+		stalls:     newStallTracker(),
+		retry:      cfg.Retry,
+		repo:       repo,
+		cache:      cache,
+		validate:   validator.New(),
 	}
 }
 
 func (c *Consumer) Start(ctx context.Context) {
-	log.Println("Kafka consumer started...")
+	log.Printf("Kafka consumer started (обработчиков: %d)...", c.workers)
+	defer close(c.done)
 	defer log.Println("Kafka consumer выходит из цикла")
-	
+
+	go c.committer.run(ctx)
+
+	// Запускаем обработчиков; каждый читает свою очередь сообщений
+	queues := make([]chan kafka.Message, c.workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan kafka.Message, 64)
+		wg.Add(1)
+		go func(queue <-chan kafka.Message) {
+			defer wg.Done()
+			c.worker(ctx, queue)
+		}(queues[i])
+	}
+	// При выходе закрываем очереди и ждём, пока обработчики завершат текущие сообщения
+	defer func() {
+		for _, q := range queues {
+			close(q)
+		}
+		wg.Wait()
+	}()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -52,11 +138,12 @@ func (c *Consumer) Start(ctx context.Context) {
 			// Продолжаем обработку
 		}
 
-		// Устанавливаем короткий таймаут для ReadMessage
+		// Устанавливаем короткий таймаут для FetchMessage.
+		// Offset коммитится явно, только после сохранения заказа или отправки в DLQ.
 		msgCtx, msgCancel := context.WithTimeout(ctx, 1*time.Second)
-		m, err := c.reader.ReadMessage(msgCtx)
+		m, err := c.reader.FetchMessage(msgCtx)
 		msgCancel()
-		
+
 		if err != nil {
 			// Проверяем, если контекст отменён
 			if ctx.Err() != nil {
@@ -71,27 +158,224 @@ func (c *Consumer) Start(ctx context.Context) {
 			continue
 		}
 
-		var order models.Order
-		if err := json.Unmarshal(m.Value, &order); err != nil {
-			log.Printf("Ошибка парсинга JSON: %v", err)
-			continue
+		c.offsets.Track(m)
+		select {
+		case queues[c.workerFor(m)] <- m:
+		case <-ctx.Done():
+			log.Printf("Kafka consumer получил сигнал остановки: %v", ctx.Err())
+			return
 		}
+	}
+}
 
-		if err := c.validate.Struct(order); err != nil {
-			log.Printf("Ошибка валидации данных: %v", err)
-			continue
+// worker обрабатывает сообщения своей очереди последовательно, накапливая
+// их в пакеты до BatchSize сообщений или до истечения BatchTimeout
+func (c *Consumer) worker(ctx context.Context, queue <-chan kafka.Message) {
+	batch := make([]kafka.Message, 0, c.batchSize)
+	timer := time.NewTimer(c.batchWait)
+	timer.Stop()
+	defer timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		c.processBatch(ctx, batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case m, ok := <-queue:
+			if !ok {
+				// Остановка — недописанный пакет не коммитится и будет перечитан
+				return
+			}
+			batch = append(batch, m)
+			if len(batch) >= c.batchSize {
+				flush()
+			} else if len(batch) == 1 {
+				timer.Reset(c.batchWait)
+			}
+		case <-timer.C:
+			if len(batch) > 0 {
+				flush()
+			}
 		}
+	}
+}
+
+// processBatch — сохранить пакет сообщений одной транзакцией.
+// Если пакет целиком сохранить не удалось, сообщения обрабатываются по одному,
+// чтобы в DLQ попали только проблемные.
+func (c *Consumer) processBatch(ctx context.Context, msgs []kafka.Message) {
+	if ctx.Err() != nil {
+		return
+	}
+	if len(msgs) == 1 {
+		c.handle(ctx, msgs[0])
+		return
+	}
 
-		if err := c.repo.CreateOrder(ctx, &order); err != nil {
-			log.Printf("Ошибка сохранения заказа в БД: %v", err)
+	orders := make([]models.Order, 0, len(msgs))
+	valid := make([]kafka.Message, 0, len(msgs))
+	for _, m := range msgs {
+		order, stage, err := c.decode(m)
+		if err != nil {
+			c.finish(ctx, m, stage, err)
 			continue
 		}
+		orders = append(orders, order)
+		valid = append(valid, m)
+	}
+	if len(orders) == 0 {
+		return
+	}
+
+	err := c.retry.Do(ctx, func() error {
+		return c.repo.CreateOrders(ctx, orders)
+	}, database.IsTransient, func(attempt int, delay time.Duration, err error) {
+		log.Printf("Временная ошибка сохранения пакета из %d заказов (попытка %d), повтор через %v: %v", len(orders), attempt, delay, err)
+	})
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка сохранения пакета из %d заказов, обрабатываем по одному: %v", len(orders), err)
+		for _, m := range valid {
+			c.handle(ctx, m)
+		}
+		return
+	}
+
+	// CreateOrders заполняет ID и статус только первого вхождения заказа в пакете,
+	// поэтому повторы того же order_uid в кэш не кладутся
+	cached := make(map[string]struct{}, len(orders))
+	for i, order := range orders {
+		if _, ok := cached[order.OrderUID]; !ok {
+			c.cache.Set(order.OrderUID, order)
+			cached[order.OrderUID] = struct{}{}
+		}
+		c.complete(valid[i])
+	}
+	log.Printf("Пакет из %d заказов успешно обработан", len(orders))
+}
+
+// handle — обработать одно сообщение: сохранить заказ или отправить сообщение в DLQ
+func (c *Consumer) handle(ctx context.Context, m kafka.Message) {
+	if ctx.Err() != nil {
+		return
+	}
+	stage, err := processUntilCommittable(ctx, c.deadLetter, c.retry, c.stalls, m, func() (string, error) {
+		return c.processMessage(ctx, m)
+	})
+	c.finish(ctx, m, stage, err)
+}
+
+// finish — завершить обработку сообщения: при ошибке отправить его в DLQ
+// и отметить обработанным. Если обработка прервана остановкой, offset не коммитится.
+func (c *Consumer) finish(ctx context.Context, m kafka.Message, stage string, err error) {
+	if ctx.Err() != nil {
+		log.Printf("Обработка %s/%d@%d прервана остановкой consumer'а", m.Topic, m.Partition, m.Offset)
+		return
+	}
+	if err != nil && !c.reject(ctx, m, stage, err) {
+		return
+	}
+	c.complete(m)
+}
+
+// complete — отметить сообщение обработанным.
+// Коммитится только непрерывно обработанный префикс партиции.
+func (c *Consumer) complete(m kafka.Message) {
+	if last, ok := c.offsets.Done(m); ok {
+		c.committer.MarkDone(last)
+	}
+}
+
+// workerFor выбирает обработчика по ключу сообщения, чтобы сообщения одного
+// заказа обрабатывались строго по порядку. Сообщения без ключа
+// распределяются по партиции.
+func (c *Consumer) workerFor(m kafka.Message) int {
+	h := fnv.New32a()
+	if len(m.Key) > 0 {
+		h.Write(m.Key)
+	} else {
+		h.Write([]byte{byte(m.Partition >> 8), byte(m.Partition)})
+	}
+	return int(h.Sum32() % uint32(c.workers))
+}
+
+// decode — разобрать и провалидировать заказ из сообщения.
+// При ошибке возвращает стадию, на которой она произошла.
+func (c *Consumer) decode(m kafka.Message) (models.Order, string, error) {
+	var order models.Order
+	if err := json.Unmarshal(m.Value, &order); err != nil {
+		log.Printf("Ошибка парсинга JSON: %v", err)
+		return order, StageParse, err
+	}
+
+	if err := c.validate.Struct(order); err != nil {
+		log.Printf("Ошибка валидации данных: %v", err)
+		return order, StageValidate, err
+	}
+	return order, "", nil
+}
 
-		c.cache.Set(order.OrderUID, order)
-		log.Printf("Заказ %s успешно обработан", order.OrderUID)
+// processMessage — разобрать, провалидировать и сохранить заказ из сообщения.
+// При ошибке возвращает стадию, на которой она произошла.
+func (c *Consumer) processMessage(ctx context.Context, m kafka.Message) (string, error) {
+	order, stage, err := c.decode(m)
+	if err != nil {
+		return stage, err
 	}
+
+	err = c.retry.Do(ctx, func() error {
+		return c.repo.CreateOrder(ctx, &order)
+	}, database.IsTransient, func(attempt int, delay time.Duration, err error) {
+		log.Printf("Временная ошибка сохранения заказа %s (попытка %d), повтор через %v: %v", order.OrderUID, attempt, delay, err)
+	})
+	if errors.Is(err, database.ErrOrderConflict) {
+		// Повтор не поможет: заказ уже сохранён с другим содержимым
+		log.Printf("Конфликт при сохранении заказа: %v", err)
+		return StageConflict, err
+	}
+	if err != nil {
+		log.Printf("Ошибка сохранения заказа в БД: %v", err)
+		return StagePersist, err
+	}
+
+	c.cache.Set(order.OrderUID, order)
+	log.Printf("Заказ %s успешно обработан", order.OrderUID)
+	return "", nil
+}
+
+// reject — отправить сообщение, которое не удалось обработать, в dead-letter топик
+// (см. rejectMessage)
+func (c *Consumer) reject(ctx context.Context, m kafka.Message, stage string, cause error) bool {
+	return rejectMessage(ctx, c.deadLetter, c.retry, c.stalls, m, stage, cause)
+}
+
+// Stalls возвращает сообщения, которые не удаётся обработать или отправить в DLQ:
+// offset'ы их партиций не коммитятся, пока обработка повторяется
+func (c *Consumer) Stalls() []Stall {
+	return c.stalls.list()
+}
+
+// Done возвращает канал, который закрывается после выхода Start из цикла обработки
+func (c *Consumer) Done() <-chan struct{} {
+	return c.done
+}
+
+// Flush — закоммитить offset'ы всех обработанных сообщений.
+// Вызывается при graceful shutdown после остановки Start и до Close.
+func (c *Consumer) Flush(ctx context.Context) error {
+	return c.committer.Flush(ctx)
 }
 
 func (c *Consumer) Close() {
 	c.reader.Close()
+	if c.deadLetter != nil {
+		if err := c.deadLetter.Close(); err != nil {
+			log.Printf("Ошибка закрытия DLQ writer: %v", err)
+		}
+	}
 }
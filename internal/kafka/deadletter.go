@@ -0,0 +1,162 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/highdolen/L0/internal/database"
+	"github.com/segmentio/kafka-go"
+)
+
+// Стадии обработки, на которых сообщение может попасть в dead-letter топик
+const (
+	StageParse    = "parse"
+	StageValidate = "validate"
+	StagePersist  = "persist"
+	// StageConflict — заказ с таким order_uid уже сохранён с другим содержимым
+	StageConflict = "conflict"
+	// StageNotFound — событие статуса относится к заказу, которого нет в БД
+	StageNotFound = "not_found"
+	// StageTransition — переход статуса заказа недопустим
+	StageTransition = "transition"
+)
+
+// Заголовки, которыми помечается сообщение в dead-letter топике
+const (
+	HeaderStage             = "x-dlq-stage"
+	HeaderError             = "x-dlq-error"
+	HeaderOriginalTopic     = "x-dlq-original-topic"
+	HeaderOriginalPartition = "x-dlq-original-partition"
+	HeaderOriginalOffset    = "x-dlq-original-offset"
+	HeaderFailedAt          = "x-dlq-failed-at"
+)
+
+// DeadLetterPublisher отправляет необработанные сообщения в dead-letter топик
+type DeadLetterPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewDeadLetterPublisher создает publisher для указанного топика
+func NewDeadLetterPublisher(brokers []string, topic string) *DeadLetterPublisher {
+	return &DeadLetterPublisher{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			Balancer:               &kafka.Hash{}, // сохраняем ключ → партиция, как в исходном топике
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+			// Сообщения публикуются по одному и синхронно: без этого writer
+			// ждал бы заполнения пакета до секунды (BatchTimeout по умолчанию)
+			BatchSize:    1,
+			BatchTimeout: 5 * time.Millisecond,
+		},
+	}
+}
+
+// Publish — отправить исходное сообщение в DLQ вместе с описанием ошибки.
+// Ключ, значение и исходные заголовки сохраняются без изменений, чтобы
+// сообщение можно было переиграть в исходный топик.
+func (p *DeadLetterPublisher) Publish(ctx context.Context, m kafka.Message, stage string, cause error) error {
+	headers := make([]kafka.Header, 0, len(m.Headers)+6)
+	headers = append(headers, m.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderStage, Value: []byte(stage)},
+		kafka.Header{Key: HeaderError, Value: []byte(errorText(cause))},
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(m.Topic)},
+		kafka.Header{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(m.Partition))},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(m.Offset, 10))},
+		kafka.Header{Key: HeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339Nano))},
+	)
+
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:     m.Key,
+		Value:   m.Value,
+		Headers: headers,
+	})
+}
+
+// Close — закрыть writer, дождавшись отправки буферизованных сообщений
+func (p *DeadLetterPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// droppable сообщает, можно ли без DLQ отбросить сообщение, не обработанное на стадии stage.
+// Отбрасываются сообщения с детерминированной ошибкой, которую повторное чтение
+// не исправит: неразбираемые, невалидные, конфликтующие с сохранённым заказом,
+// с недопустимым переходом статуса и с постоянной ошибкой БД (нарушение
+// ограничений, некорректные данные). Временные ошибки БД, конкурентное изменение
+// статуса и отсутствие заказа повторяются (см. processUntilCommittable).
+func droppable(stage string, err error) bool {
+	switch stage {
+	case StageParse, StageValidate, StageConflict, StageTransition:
+		return true
+	case StagePersist:
+		return !database.IsTransient(err) && !errors.Is(err, database.ErrStatusChanged)
+	default:
+		return false
+	}
+}
+
+// processUntilCommittable выполняет process, пока сообщение нельзя закоммитить:
+// DLQ не настроен, а ошибка не из тех, что можно отбросить (см. droppable).
+// Незавершённое сообщение не даёт закоммитить более поздние в его партиции,
+// поэтому обработка повторяется с задержкой, а сообщение отмечается в stalls
+// для проверки состояния. Возвращает стадию и ошибку последней попытки
+// или ошибку контекста, если consumer остановлен.
+func processUntilCommittable(ctx context.Context, dlq *DeadLetterPublisher, retry RetryPolicy, stalls *stallTracker, m kafka.Message, process func() (string, error)) (string, error) {
+	var stage string
+	policy := retry
+	policy.MaxAttempts = 0
+	err := policy.Do(ctx, func() error {
+		var err error
+		stage, err = process()
+		return err
+	}, func(err error) bool {
+		return dlq == nil && !droppable(stage, err) && ctx.Err() == nil
+	}, func(attempt int, delay time.Duration, err error) {
+		stalls.set(m, stage, err)
+		log.Printf("DLQ не настроен, сообщение %s/%d@%d не обработано (стадия %s, попытка %d): партиция не коммитится, повтор через %v: %v", m.Topic, m.Partition, m.Offset, stage, attempt, delay, err)
+	})
+	stalls.clear(m)
+	return stage, err
+}
+
+// rejectMessage — отправить сообщение, которое не удалось обработать, в dead-letter топик.
+// Отправка повторяется до успеха, так как без неё нельзя закоммитить offset;
+// пока она не удалась, сообщение отмечается в stalls.
+// Если DLQ не настроен (dlq == nil), сообщение отбрасывается с записью в лог:
+// сюда попадают только те, что можно отбросить (см. processUntilCommittable).
+// Возвращает false, если consumer остановлен раньше, чем сообщение попало в DLQ.
+func rejectMessage(ctx context.Context, dlq *DeadLetterPublisher, retry RetryPolicy, stalls *stallTracker, m kafka.Message, stage string, cause error) bool {
+	if dlq == nil {
+		log.Printf("DLQ не настроен, сообщение %s/%d@%d отброшено (стадия %s): %v", m.Topic, m.Partition, m.Offset, stage, cause)
+		return true
+	}
+
+	policy := retry
+	policy.MaxAttempts = 0
+	err := policy.Do(ctx, func() error {
+		return dlq.Publish(ctx, m, stage, cause)
+	}, func(error) bool { return ctx.Err() == nil }, func(attempt int, delay time.Duration, err error) {
+		stalls.set(m, stage, fmt.Errorf("отправка в DLQ: %w", err))
+		log.Printf("Ошибка отправки сообщения %s/%d@%d в DLQ (попытка %d), повтор через %v: %v", m.Topic, m.Partition, m.Offset, attempt, delay, err)
+	})
+	stalls.clear(m)
+	if err != nil {
+		log.Printf("Сообщение %s/%d@%d не отправлено в DLQ: %v", m.Topic, m.Partition, m.Offset, err)
+		return false
+	}
+	log.Printf("Сообщение %s/%d@%d отправлено в DLQ (стадия %s)", m.Topic, m.Partition, m.Offset, stage)
+	return true
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
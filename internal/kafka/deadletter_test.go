@@ -0,0 +1,110 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/highdolen/L0/internal/database"
+	"github.com/jackc/pgconn"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestDroppable(t *testing.T) {
+	tests := []struct {
+		name  string
+		stage string
+		err   error
+		want  bool
+	}{
+		{"не JSON", StageParse, errors.New("unexpected end of JSON input"), true},
+		{"невалидное", StageValidate, errors.New("order_uid required"), true},
+		{"конфликт", StageConflict, database.ErrOrderConflict, true},
+		{"недопустимый переход", StageTransition, errors.New("paid → created"), true},
+		{"слишком длинное значение", StagePersist, &pgconn.PgError{Code: "22001"}, true},
+		{"нарушение ограничения", StagePersist, &pgconn.PgError{Code: "23514"}, true},
+		{"обрыв соединения", StagePersist, io.ErrUnexpectedEOF, false},
+		{"deadlock", StagePersist, &pgconn.PgError{Code: "40P01"}, false},
+		{"статус изменён одновременно", StagePersist, fmt.Errorf("update: %w", database.ErrStatusChanged), false},
+		{"заказ не найден", StageNotFound, errors.New("not found"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := droppable(tt.stage, tt.err); got != tt.want {
+				t.Errorf("droppable(%s, %v) = %v, ожидалось %v", tt.stage, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessUntilCommittable(t *testing.T) {
+	retry := RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	m := kafka.Message{Topic: "orders", Partition: 2, Offset: 42}
+
+	tests := []struct {
+		name      string
+		results   []error // ошибки стадии persist по попыткам, затем успех
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "успех", wantCalls: 1},
+		{name: "временная ошибка до восстановления БД", results: []error{io.ErrUnexpectedEOF, io.ErrUnexpectedEOF}, wantCalls: 3},
+		{name: "постоянная ошибка не повторяется", results: []error{&pgconn.PgError{Code: "23505"}}, wantErr: true, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stalls := newStallTracker()
+			calls := 0
+			stage, err := processUntilCommittable(context.Background(), nil, retry, stalls, m, func() (string, error) {
+				calls++
+				if calls > 1 && len(stalls.list()) != 1 {
+					t.Errorf("попытка %d: сообщение не отмечено застрявшим", calls)
+				}
+				if calls <= len(tt.results) {
+					return StagePersist, tt.results[calls-1]
+				}
+				return "", nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("processUntilCommittable = %q, %v", stage, err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("попыток %d, ожидалось %d", calls, tt.wantCalls)
+			}
+			if stalls := stalls.list(); len(stalls) != 0 {
+				t.Errorf("после завершения остались застрявшие сообщения: %+v", stalls)
+			}
+		})
+	}
+}
+
+func TestProcessUntilCommittableStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	retry := RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	m := kafka.Message{Topic: "statuses", Partition: 0, Offset: 7}
+	stalls := newStallTracker()
+
+	calls := 0
+	_, err := processUntilCommittable(ctx, nil, retry, stalls, m, func() (string, error) {
+		calls++
+		if calls == 3 {
+			got := stalls.list()
+			if len(got) != 1 || got[0].Offset != m.Offset || got[0].Stage != StageNotFound || got[0].Attempts != 2 {
+				t.Errorf("застрявшие сообщения: %+v", got)
+			}
+			cancel()
+		}
+		return StageNotFound, errors.New("заказ не найден")
+	})
+	if err == nil || calls != 3 {
+		t.Fatalf("processUntilCommittable вернул %v после %d попыток, ожидалась остановка после 3", err, calls)
+	}
+	if len(stalls.list()) != 0 {
+		t.Errorf("после остановки остались застрявшие сообщения")
+	}
+}
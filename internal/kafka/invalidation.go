@@ -0,0 +1,188 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/highdolen/L0/internal/cache"
+	"github.com/segmentio/kafka-go"
+)
+
+// InvalidationEvent — команда инвалидации кэша, рассылаемая всем репликам
+type InvalidationEvent struct {
+	// NodeID — реплика, на которой произошла инвалидация; она своё событие пропускает
+	NodeID string `json:"node_id"`
+	// OrderUID — заказ для удаления из кэша; пусто вместе с All — полная очистка
+	OrderUID string `json:"order_uid,omitempty"`
+	// All — очистить кэш полностью
+	All bool `json:"all,omitempty"`
+	// Indexes — название вторичного индекса → значение: заказы с этим значением
+	// удаляются из кэша, а их набор перестаёт считаться полным
+	Indexes map[string]string `json:"indexes,omitempty"`
+	At      time.Time         `json:"at"`
+}
+
+// InvalidationCache — локальный кэш, к которому применяются чужие инвалидации
+type InvalidationCache interface {
+	Delete(uid string)
+	InvalidateAll()
+	DeleteIndexed(idx cache.Index, value string) []string
+}
+
+// InvalidationBus рассылает инвалидации кэша через Kafka и применяет события
+// других реплик к локальному кэшу.
+//
+// Каждая реплика должна получить каждое событие, поэтому чтение идёт без
+// consumer group, с конца партиции 0: события, отправленные до старта реплики,
+// ей не нужны — её кэш в этот момент ещё пуст или загружается из БД.
+// Все события пишутся в партицию 0, чтобы топик мог иметь любое число партиций.
+//
+// События отправляются асинхронно: рассылка идёт на каждый заказ из Kafka и не
+// должна задерживать его обработку. Ошибки отправки только логируются — на
+// других репликах запись истечёт по TTL.
+type InvalidationBus struct {
+	nodeID string
+	cache  InvalidationCache
+	writer *kafka.Writer
+	reader *kafka.Reader
+	done   chan struct{}
+}
+
+// NewInvalidationBus создает шину инвалидации для топика
+func NewInvalidationBus(brokers []string, topic, nodeID string, cache InvalidationCache) *InvalidationBus {
+	return &InvalidationBus{
+		nodeID: nodeID,
+		cache:  cache,
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			Balancer:               firstPartition{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+			Async:                  true,
+			BatchTimeout:           10 * time.Millisecond,
+			Completion: func(messages []kafka.Message, err error) {
+				if err != nil {
+					log.Printf("Ошибка рассылки %d событий инвалидации: %v", len(messages), err)
+				}
+			},
+		},
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   brokers,
+			Topic:     topic,
+			Partition: 0,
+			MinBytes:  1,
+			MaxBytes:  1e6, // 1MB
+			MaxWait:   500 * time.Millisecond,
+		}),
+		done: make(chan struct{}),
+	}
+}
+
+// Broadcast отправляет остальным репликам инвалидацию заказа uid
+// (пустой uid — полная очистка кэша)
+func (b *InvalidationBus) Broadcast(ctx context.Context, uid string) error {
+	return b.send(ctx, InvalidationEvent{OrderUID: uid, All: uid == ""})
+}
+
+// BroadcastIndexed отправляет остальным репликам инвалидацию заказа uid (может
+// быть пустым) и заказов с заданными значениями вторичных индексов
+func (b *InvalidationBus) BroadcastIndexed(ctx context.Context, uid string, indexes map[cache.Index]string) error {
+	ev := InvalidationEvent{OrderUID: uid, Indexes: make(map[string]string, len(indexes))}
+	for idx, value := range indexes {
+		ev.Indexes[idx.String()] = value
+	}
+	return b.send(ctx, ev)
+}
+
+func (b *InvalidationBus) send(ctx context.Context, ev InvalidationEvent) error {
+	ev.NodeID = b.nodeID
+	ev.At = time.Now().UTC()
+	value, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return b.writer.WriteMessages(ctx, kafka.Message{Key: []byte(ev.OrderUID), Value: value})
+}
+
+// Run читает события инвалидации до отмены ctx
+func (b *InvalidationBus) Run(ctx context.Context) {
+	defer close(b.done)
+
+	if err := b.reader.SetOffset(kafka.LastOffset); err != nil {
+		log.Printf("Ошибка установки offset'а топика инвалидации: %v", err)
+		return
+	}
+	log.Printf("Подписка на инвалидации кэша запущена (узел %s)", b.nodeID)
+
+	for {
+		m, err := b.reader.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+				log.Println("Подписка на инвалидации кэша остановлена")
+				return
+			}
+			log.Printf("Ошибка чтения события инвалидации: %v", err)
+			// Топик мог ещё не создаться — ждём и пробуем снова
+			select {
+			case <-time.After(time.Second):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		b.apply(m)
+	}
+}
+
+// apply применяет событие к локальному кэшу, пропуская собственные события узла
+func (b *InvalidationBus) apply(m kafka.Message) {
+	var ev InvalidationEvent
+	if err := json.Unmarshal(m.Value, &ev); err != nil {
+		log.Printf("Некорректное событие инвалидации (offset %d): %v", m.Offset, err)
+		return
+	}
+	if ev.NodeID == b.nodeID {
+		return
+	}
+
+	if ev.All {
+		b.cache.InvalidateAll()
+		log.Printf("Кэш очищен по событию узла %s", ev.NodeID)
+		return
+	}
+	if ev.OrderUID != "" {
+		b.cache.Delete(ev.OrderUID)
+		log.Printf("Заказ %s инвалидирован по событию узла %s", ev.OrderUID, ev.NodeID)
+	}
+	for name, value := range ev.Indexes {
+		idx, ok := cache.ParseIndex(name)
+		if !ok {
+			log.Printf("Неизвестный индекс %q в событии инвалидации (offset %d)", name, m.Offset)
+			continue
+		}
+		removed := b.cache.DeleteIndexed(idx, value)
+		log.Printf("Заказы с %s=%s инвалидированы по событию узла %s (удалено %d)", idx, value, ev.NodeID, len(removed))
+	}
+}
+
+// Done закрывается, когда Run завершился
+func (b *InvalidationBus) Done() <-chan struct{} {
+	return b.done
+}
+
+// Close закрывает writer и reader шины
+func (b *InvalidationBus) Close() error {
+	return errors.Join(b.writer.Close(), b.reader.Close())
+}
+
+// firstPartition направляет все сообщения в первую партицию топика
+type firstPartition struct{}
+
+func (firstPartition) Balance(msg kafka.Message, partitions ...int) int {
+	return partitions[0]
+}
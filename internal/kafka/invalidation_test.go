@@ -0,0 +1,57 @@
+package kafka
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/highdolen/L0/internal/cache"
+	"github.com/highdolen/L0/internal/models"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestInvalidationBusApplyIngest(t *testing.T) {
+	c := cache.NewWithOptions(time.Hour, cache.Options{NegativeTTL: time.Hour})
+	defer c.Close()
+	bus := &InvalidationBus{nodeID: "b", cache: c}
+
+	old := models.Order{OrderUID: "old", TrackNumber: "T1", CustomerID: "alice"}
+	c.SetIndexed(cache.ByCustomer, "alice", []models.Order{old})
+	c.SetIndexed(cache.ByTrackNumber, "T2", nil)
+	c.SetNotFound("new")
+
+	event := func(ev InvalidationEvent) kafka.Message {
+		value, err := json.Marshal(ev)
+		if err != nil {
+			t.Fatalf("json.Marshal: %v", err)
+		}
+		return kafka.Message{Value: value}
+	}
+
+	// Собственное событие узла пропускается
+	bus.apply(event(InvalidationEvent{NodeID: "b", OrderUID: "new"}))
+	if _, status := c.Lookup("new"); status != cache.NegativeHit {
+		t.Fatalf("собственное событие применено: статус %v", status)
+	}
+
+	// Реплика a сохранила новый заказ покупателя alice с track_number T2
+	bus.apply(event(InvalidationEvent{
+		NodeID:   "a",
+		OrderUID: "new",
+		Indexes: map[string]string{
+			cache.ByCustomer.String():    "alice",
+			cache.ByTrackNumber.String(): "T2",
+			"unknown":                    "x",
+		},
+	}))
+
+	if _, status := c.Lookup("new"); status != cache.Miss {
+		t.Errorf("негативная запись не снята: статус %v", status)
+	}
+	if _, ok := c.LookupIndex(cache.ByCustomer, "alice"); ok {
+		t.Errorf("набор заказов покупателя остался полным")
+	}
+	if _, ok := c.LookupIndex(cache.ByTrackNumber, "T2"); ok {
+		t.Errorf("набор заказов по track_number остался полным")
+	}
+}
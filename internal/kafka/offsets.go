@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// offsetTracker отслеживает сообщения, которые обрабатываются параллельно, и
+// определяет, до какого offset'а каждая партиция обработана без пропусков.
+// Коммитить можно только этот offset: всё, что раньше него, уже сохранено
+// или отправлено в DLQ.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[topicPartition]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	inflight []int64                 // offset'ы в порядке получения (по возрастанию)
+	done     map[int64]kafka.Message // обработанные, но ещё не закоммиченные
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[topicPartition]*partitionOffsets),
+	}
+}
+
+// Track — зарегистрировать полученное сообщение до передачи его обработчику
+func (t *offsetTracker) Track(m kafka.Message) {
+	key := topicPartition{topic: m.Topic, partition: m.Partition}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[key]
+	// Offset не больше уже полученного означает перечитывание партиции
+	// после ребалансировки — старое состояние больше не актуально
+	if !ok || (len(p.inflight) > 0 && m.Offset <= p.inflight[len(p.inflight)-1]) {
+		p = &partitionOffsets{done: make(map[int64]kafka.Message)}
+		t.partitions[key] = p
+	}
+	p.inflight = append(p.inflight, m.Offset)
+}
+
+// Done — отметить сообщение обработанным. Возвращает последнее сообщение
+// непрерывно обработанного префикса партиции, если он продвинулся.
+func (t *offsetTracker) Done(m kafka.Message) (kafka.Message, bool) {
+	key := topicPartition{topic: m.Topic, partition: m.Partition}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[key]
+	if !ok || len(p.inflight) == 0 || m.Offset < p.inflight[0] {
+		// Сообщение из состояния партиции, сброшенного при ребалансировке
+		return kafka.Message{}, false
+	}
+	p.done[m.Offset] = m
+
+	var (
+		last     kafka.Message
+		advanced bool
+	)
+	for len(p.inflight) > 0 {
+		head, ok := p.done[p.inflight[0]]
+		if !ok {
+			break
+		}
+		delete(p.done, p.inflight[0])
+		p.inflight = p.inflight[1:]
+		last, advanced = head, true
+	}
+	return last, advanced
+}
@@ -0,0 +1,80 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	msg := func(partition int, offset int64) kafka.Message {
+		return kafka.Message{Topic: "orders", Partition: partition, Offset: offset}
+	}
+
+	// step — получить (track) или завершить (done) сообщение; для done проверяется,
+	// до какого offset'а продвинулся непрерывный префикс (-1 — не продвинулся)
+	type step struct {
+		track     bool
+		partition int
+		offset    int64
+		want      int64
+	}
+	track := func(p int, o int64) step { return step{track: true, partition: p, offset: o} }
+	done := func(p int, o int64, want int64) step { return step{partition: p, offset: o, want: want} }
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"in order", []step{
+			track(0, 1), track(0, 2),
+			done(0, 1, 1), done(0, 2, 2),
+		}},
+		{"out of order", []step{
+			track(0, 1), track(0, 2), track(0, 3),
+			done(0, 3, -1), done(0, 2, -1), done(0, 1, 3),
+		}},
+		{"gap in offsets", []step{
+			// Offset'ы партиции могут идти с пропусками (compaction, транзакции)
+			track(0, 10), track(0, 15), track(0, 20),
+			done(0, 15, -1), done(0, 10, 15), done(0, 20, 20),
+		}},
+		{"partitions are independent", []step{
+			track(0, 1), track(1, 1), track(0, 2),
+			done(0, 2, -1), done(1, 1, 1), done(0, 1, 2),
+		}},
+		{"rebalance resets partition", []step{
+			track(0, 5), track(0, 6),
+			// Партиция перечитывается с 5 после ребалансировки: старое состояние сброшено
+			track(0, 5),
+			done(0, 6, -1), done(0, 5, 5),
+		}},
+		{"stale message after reset", []step{
+			track(0, 5), track(0, 6), track(0, 3),
+			// Сообщение 5 из сброшенного состояния не должно продвигать партицию
+			done(0, 5, -1), done(0, 3, 3),
+		}},
+		{"unknown partition", []step{
+			done(0, 1, -1),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newOffsetTracker()
+			for i, s := range tt.steps {
+				if s.track {
+					tr.Track(msg(s.partition, s.offset))
+					continue
+				}
+				last, ok := tr.Done(msg(s.partition, s.offset))
+				got := int64(-1)
+				if ok {
+					got = last.Offset
+				}
+				if got != s.want {
+					t.Fatalf("шаг %d: Done(%d/%d) продвинул до %d, want %d", i, s.partition, s.offset, got, s.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy — параметры повторов с экспоненциальной задержкой
+type RetryPolicy struct {
+	// MaxAttempts — максимальное число попыток, включая первую.
+	// Значение <= 0 означает повторы до отмены контекста.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Do выполняет fn, повторяя её, пока retryable(err) == true и попытки не исчерпаны.
+// Возвращает последнюю ошибку fn или ошибку контекста, если он отменён во время ожидания.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error, retryable func(error) bool, onRetry func(attempt int, delay time.Duration, err error)) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !retryable(err) {
+			return err
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		if onRetry != nil {
+			onRetry(attempt, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff — задержка перед попыткой attempt+1: экспонента от InitialBackoff,
+// ограниченная MaxBackoff, со случайным разбросом в диапазоне [d/2, d]
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	if d <= 0 {
+		d = 100 * time.Millisecond
+	}
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			d = p.MaxBackoff
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,98 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTemporary = errors.New("temporary")
+
+func TestRetryPolicyBackoffBounds(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	tests := []struct {
+		attempt int
+		full    time.Duration // задержка без разброса
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{50, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			d := p.backoff(tt.attempt)
+			if d < tt.full/2 || d > tt.full {
+				t.Fatalf("backoff(%d) = %v, want in [%v, %v]", tt.attempt, d, tt.full/2, tt.full)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyBackoffDefault(t *testing.T) {
+	d := RetryPolicy{}.backoff(1)
+	if d < 50*time.Millisecond || d > 100*time.Millisecond {
+		t.Fatalf("backoff without InitialBackoff = %v, want in [50ms, 100ms]", d)
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	retryable := func(err error) bool { return errors.Is(err, errTemporary) }
+	permanent := errors.New("permanent")
+
+	tests := []struct {
+		name      string
+		errs      []error // результат каждой попытки; дальше — nil
+		wantErr   error
+		wantCalls int
+	}{
+		{"success", nil, nil, 1},
+		{"recovers", []error{errTemporary, errTemporary}, nil, 3},
+		{"exhausted", []error{errTemporary, errTemporary, errTemporary, errTemporary}, errTemporary, 3},
+		{"permanent", []error{permanent}, permanent, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, retries := 0, 0
+			err := p.Do(context.Background(), func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			}, retryable, func(int, time.Duration, error) { retries++ })
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls || retries != tt.wantCalls-1 {
+				t.Fatalf("calls = %d, retries = %d, want %d and %d", calls, retries, tt.wantCalls, tt.wantCalls-1)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDoCancelled(t *testing.T) {
+	// Без ограничения попыток Do выходит только по отмене контекста
+	p := RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Do(ctx, func() error { return errTemporary }, func(error) bool { return true }, nil)
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Do не завершился после отмены контекста")
+	}
+}
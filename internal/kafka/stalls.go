@@ -0,0 +1,82 @@
+package kafka
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Stall — сообщение, которое не удаётся ни обработать, ни отправить в DLQ.
+// Пока оно не завершено, offset'ы его партиции не коммитятся.
+type Stall struct {
+	Topic     string    `json:"topic"`
+	Partition int       `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Stage     string    `json:"stage"`
+	Error     string    `json:"error"`
+	Since     time.Time `json:"since"`
+	Attempts  int       `json:"attempts"`
+}
+
+type stallKey struct {
+	topicPartition
+	offset int64
+}
+
+// stallTracker хранит сообщения, обработка которых повторяется, для проверки состояния
+type stallTracker struct {
+	mu     sync.Mutex
+	stalls map[stallKey]Stall
+}
+
+func newStallTracker() *stallTracker {
+	return &stallTracker{stalls: make(map[stallKey]Stall)}
+}
+
+// set — отметить сообщение застрявшим или обновить причину очередной неудачи
+func (t *stallTracker) set(m kafka.Message, stage string, err error) {
+	key := stallKey{topicPartition{topic: m.Topic, partition: m.Partition}, m.Offset}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.stalls[key]
+	if !ok {
+		s = Stall{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset, Since: time.Now()}
+	}
+	s.Stage = stage
+	s.Error = errorText(err)
+	s.Attempts++
+	t.stalls[key] = s
+}
+
+// clear — сообщение завершено или обработка прервана остановкой
+func (t *stallTracker) clear(m kafka.Message) {
+	key := stallKey{topicPartition{topic: m.Topic, partition: m.Partition}, m.Offset}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.stalls, key)
+}
+
+// list возвращает застрявшие сообщения, упорядоченные по топику, партиции и offset'у
+func (t *stallTracker) list() []Stall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stalls := make([]Stall, 0, len(t.stalls))
+	for _, s := range t.stalls {
+		stalls = append(stalls, s)
+	}
+	slices.SortFunc(stalls, func(a, b Stall) int {
+		return cmp.Or(
+			cmp.Compare(a.Topic, b.Topic),
+			cmp.Compare(a.Partition, b.Partition),
+			cmp.Compare(a.Offset, b.Offset),
+		)
+	})
+	return stalls
+}
@@ -0,0 +1,208 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/highdolen/L0/internal/database"
+	"github.com/highdolen/L0/internal/models"
+	"github.com/highdolen/L0/internal/service"
+	"github.com/segmentio/kafka-go"
+)
+
+// StatusConsumerConfig — параметры consumer'а событий изменения статуса заказов
+type StatusConsumerConfig struct {
+	Brokers []string
+	Topic   string
+	// GroupID — отдельная от заказов consumer group
+	GroupID string
+	// DeadLetterTopic — топик для событий, которые не удалось применить.
+	// Если пустой, события с детерминированной ошибкой (в том числе недопустимый
+	// переход) только логируются, а применение остальных повторяется до успеха
+	// (см. processUntilCommittable).
+	DeadLetterTopic string
+	// Retry — повторы при временных ошибках БД и для событий, пришедших раньше заказа
+	Retry RetryPolicy
+	// CommitInterval — период пакетного коммита offset'ов (0 — после каждого сообщения)
+	CommitInterval time.Duration
+}
+
+// StatusService применяет изменения статусов заказов
+type StatusService interface {
+	ChangeOrderStatus(ctx context.Context, change models.StatusChange) error
+}
+
+// StatusConsumer читает события изменения статуса из отдельного топика и применяет
+// их через сервисный слой, который проверяет допустимость перехода.
+//
+// События обрабатываются последовательно: переходы одного заказа должны
+// применяться строго по порядку, а поток статусов намного меньше потока заказов.
+// Продюсер должен использовать order_uid как ключ, чтобы события заказа
+// попадали в одну партицию.
+type StatusConsumer struct {
+	reader     *kafka.Reader
+	committer  *committer
+	done       chan struct{}
+	deadLetter *DeadLetterPublisher
+	stalls     *stallTracker
+	retry      RetryPolicy
+	service    StatusService
+	validate   *validator.Validate
+}
+
+// NewStatusConsumer создает consumer событий изменения статуса
+func NewStatusConsumer(cfg StatusConsumerConfig, svc StatusService) *StatusConsumer {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.Brokers,
+		Topic:       cfg.Topic,
+		GroupID:     cfg.GroupID,
+		StartOffset: kafka.FirstOffset, // переходы нельзя пропускать
+		MinBytes:    1,
+		MaxBytes:    1e6, // 1MB
+		MaxWait:     time.Second,
+	})
+
+	var dlq *DeadLetterPublisher
+	if cfg.DeadLetterTopic != "" {
+		dlq = NewDeadLetterPublisher(cfg.Brokers, cfg.DeadLetterTopic)
+	}
+
+	return &StatusConsumer{
+		reader:     r,
+		committer:  newCommitter(r, cfg.CommitInterval),
+		done:       make(chan struct{}),
+		deadLetter: dlq,
+		stalls:     newStallTracker(),
+		retry:      cfg.Retry,
+		service:    svc,
+		validate:   validator.New(),
+	}
+}
+
+// Start читает и применяет события до отмены ctx
+func (c *StatusConsumer) Start(ctx context.Context) {
+	log.Println("Kafka consumer статусов заказов запущен...")
+	defer close(c.done)
+	defer log.Println("Kafka consumer статусов выходит из цикла")
+
+	go c.committer.run(ctx)
+
+	for {
+		if ctx.Err() != nil {
+			log.Printf("Kafka consumer статусов получил сигнал остановки: %v", ctx.Err())
+			return
+		}
+
+		msgCtx, msgCancel := context.WithTimeout(ctx, 1*time.Second)
+		m, err := c.reader.FetchMessage(msgCtx)
+		msgCancel()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Printf("Kafka consumer статусов остановлен по контексту: %v", ctx.Err())
+				return
+			}
+			if msgCtx.Err() == context.DeadlineExceeded {
+				continue
+			}
+			log.Printf("Ошибка чтения события статуса из Kafka: %v", err)
+			continue
+		}
+
+		c.handle(ctx, m)
+	}
+}
+
+// handle применяет одно событие. Событие, которое применить нельзя, отправляется
+// в DLQ; offset коммитится после применения или отправки в DLQ.
+// Следующие события нельзя коммитить раньше этого: коммит более позднего offset'а
+// пропустил бы его, поэтому handle возвращается, только когда событие завершено
+// или consumer остановлен. Если обработка прервана остановкой, offset
+// не коммитится и событие будет перечитано.
+func (c *StatusConsumer) handle(ctx context.Context, m kafka.Message) {
+	stage, err := processUntilCommittable(ctx, c.deadLetter, c.retry, c.stalls, m, func() (string, error) {
+		return c.processMessage(ctx, m)
+	})
+	if ctx.Err() != nil {
+		log.Printf("Обработка %s/%d@%d прервана остановкой consumer'а статусов", m.Topic, m.Partition, m.Offset)
+		return
+	}
+	if err != nil && !rejectMessage(ctx, c.deadLetter, c.retry, c.stalls, m, stage, err) {
+		return
+	}
+	c.committer.MarkDone(m)
+}
+
+// processMessage — разобрать, провалидировать и применить событие.
+// При ошибке возвращает стадию, на которой она произошла.
+func (c *StatusConsumer) processMessage(ctx context.Context, m kafka.Message) (string, error) {
+	var change models.StatusChange
+	if err := json.Unmarshal(m.Value, &change); err != nil {
+		log.Printf("Ошибка парсинга события статуса: %v", err)
+		return StageParse, err
+	}
+	if err := c.validate.Struct(change); err != nil {
+		log.Printf("Ошибка валидации события статуса: %v", err)
+		return StageValidate, err
+	}
+
+	err := c.retry.Do(ctx, func() error {
+		return c.service.ChangeOrderStatus(ctx, change)
+	}, retryableStatusError, func(attempt int, delay time.Duration, err error) {
+		log.Printf("Не удалось применить статус %s заказа %s (попытка %d), повтор через %v: %v", change.Status, change.OrderUID, attempt, delay, err)
+	})
+	switch {
+	case err == nil:
+		return "", nil
+	case errors.Is(err, service.ErrInvalidStatus), errors.Is(err, service.ErrMissingChangedAt):
+		log.Printf("Некорректное событие статуса заказа %s: %v", change.OrderUID, err)
+		return StageValidate, err
+	case errors.Is(err, service.ErrInvalidTransition):
+		log.Printf("Отклонено изменение статуса заказа %s: %v", change.OrderUID, err)
+		return StageTransition, err
+	case errors.Is(err, service.ErrOrderNotFound):
+		log.Printf("Заказ %s для события статуса %s не найден", change.OrderUID, change.Status)
+		return StageNotFound, err
+	default:
+		log.Printf("Ошибка применения статуса заказа %s: %v", change.OrderUID, err)
+		return StagePersist, err
+	}
+}
+
+// retryableStatusError — ошибки, после которых событие имеет смысл применить снова.
+// Заказ и его статусы приходят из разных топиков, поэтому событие может опередить
+// сам заказ: отсутствие заказа тоже повторяется, пока не исчерпаны попытки.
+func retryableStatusError(err error) bool {
+	return database.IsTransient(err) ||
+		errors.Is(err, service.ErrOrderNotFound) ||
+		errors.Is(err, database.ErrStatusChanged)
+}
+
+// Stalls возвращает событие, которое не удаётся применить или отправить в DLQ:
+// пока его применение повторяется, следующие события не читаются
+func (c *StatusConsumer) Stalls() []Stall {
+	return c.stalls.list()
+}
+
+// Done возвращает канал, который закрывается после выхода Start из цикла обработки
+func (c *StatusConsumer) Done() <-chan struct{} {
+	return c.done
+}
+
+// Flush — закоммитить offset'ы всех обработанных событий
+func (c *StatusConsumer) Flush(ctx context.Context) error {
+	return c.committer.Flush(ctx)
+}
+
+func (c *StatusConsumer) Close() {
+	c.reader.Close()
+	if c.deadLetter != nil {
+		if err := c.deadLetter.Close(); err != nil {
+			log.Printf("Ошибка закрытия DLQ writer: %v", err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/highdolen/L0/internal/database"
+	"github.com/highdolen/L0/internal/models"
+	"github.com/highdolen/L0/internal/service"
+	"github.com/segmentio/kafka-go"
+)
+
+// statusService возвращает ошибки из errs по очереди, затем nil
+type statusService struct {
+	errs  []error
+	calls int
+}
+
+func (s *statusService) ChangeOrderStatus(ctx context.Context, change models.StatusChange) error {
+	s.calls++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func TestRetryableStatusError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"заказ ещё не пришёл", service.ErrOrderNotFound, true},
+		{"статус изменён одновременно", fmt.Errorf("update: %w", database.ErrStatusChanged), true},
+		{"обрыв соединения", io.ErrUnexpectedEOF, true},
+		{"неизвестный статус", service.ErrInvalidStatus, false},
+		{"недопустимый переход", fmt.Errorf("%w: paid → created", service.ErrInvalidTransition), false},
+		{"нет changed_at", service.ErrMissingChangedAt, false},
+		{"отмена", context.Canceled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryableStatusError(tt.err); got != tt.want {
+				t.Errorf("retryableStatusError(%v) = %v, ожидалось %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusConsumerProcessMessage(t *testing.T) {
+	const valid = `{"order_uid": "order", "status": "paid", "changed_at": "2024-01-01T12:00:00Z"}`
+	attempts := 3
+
+	tests := []struct {
+		name      string
+		value     string
+		errs      []error
+		wantStage string // пусто — событие применено
+		wantCalls int
+	}{
+		{name: "применено", value: valid, wantCalls: 1},
+		{name: "не JSON", value: `{`, wantStage: StageParse},
+		{name: "нет order_uid", value: `{"status": "paid", "changed_at": "2024-01-01T12:00:00Z"}`, wantStage: StageValidate},
+		{name: "нет changed_at", value: `{"order_uid": "order", "status": "paid"}`, wantStage: StageValidate},
+		{name: "неизвестный статус", value: valid, errs: []error{service.ErrInvalidStatus}, wantStage: StageValidate, wantCalls: 1},
+		{name: "недопустимый переход", value: valid, errs: []error{service.ErrInvalidTransition}, wantStage: StageTransition, wantCalls: 1},
+		{
+			name:      "заказ пришёл после события",
+			value:     valid,
+			errs:      []error{service.ErrOrderNotFound, service.ErrOrderNotFound},
+			wantCalls: 3,
+		},
+		{
+			name:      "заказ так и не пришёл",
+			value:     valid,
+			errs:      []error{service.ErrOrderNotFound, service.ErrOrderNotFound, service.ErrOrderNotFound},
+			wantStage: StageNotFound,
+			wantCalls: attempts,
+		},
+		{
+			name:      "статус изменён одновременно",
+			value:     valid,
+			errs:      []error{database.ErrStatusChanged},
+			wantCalls: 2,
+		},
+		{
+			name:      "постоянная ошибка БД",
+			value:     valid,
+			errs:      []error{errors.New("check constraint")},
+			wantStage: StagePersist,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &statusService{errs: tt.errs}
+			c := &StatusConsumer{
+				retry:    RetryPolicy{MaxAttempts: attempts, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+				service:  svc,
+				validate: validator.New(),
+			}
+
+			stage, err := c.processMessage(context.Background(), kafka.Message{Value: []byte(tt.value)})
+			if stage != tt.wantStage || (tt.wantStage == "") != (err == nil) {
+				t.Errorf("processMessage = %q, %v; ожидалась стадия %q", stage, err, tt.wantStage)
+			}
+			if svc.calls != tt.wantCalls {
+				t.Errorf("вызовов сервиса %d, ожидалось %d", svc.calls, tt.wantCalls)
+			}
+		})
+	}
+}
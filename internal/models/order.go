@@ -62,6 +62,9 @@ type Order struct {
 	SmID              int       `json:"sm_id" db:"sm_id" validate:"gte=0"`
 	DateCreated       time.Time `json:"date_created" db:"date_created" validate:"required"`
 	OofShard          string    `json:"oof_shard" db:"oof_shard" validate:"required"`
+	// Status — текущий статус заказа. Новый заказ всегда сохраняется в статусе
+	// created, дальше статус меняется только событиями из топика статусов.
+	Status OrderStatus `json:"status" db:"status"`
 }
 
 // Validate — универсальная функция валидации для Order
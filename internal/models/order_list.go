@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor — курсор списка заказов повреждён или создан не этим сервисом
+var ErrInvalidCursor = errors.New("некорректный курсор")
+
+// OrderFilter — условия выборки списка заказов. Пустые поля не ограничивают выборку.
+type OrderFilter struct {
+	CustomerID      string
+	TrackNumber     string
+	DeliveryService string
+	// PaymentProvider и Currency — поля платежа заказа
+	PaymentProvider string
+	Currency        string
+	// Brand — в заказе есть хотя бы один товар этого бренда
+	Brand string
+	// CreatedFrom и CreatedTo — диапазон date_created: [CreatedFrom, CreatedTo)
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+
+	// After — вернуть заказы, следующие за курсором (nil — с начала списка)
+	After *OrderCursor
+	// Limit — размер страницы
+	Limit int
+}
+
+// OrderCursor — позиция в списке заказов, упорядоченном по date_created и order_uid
+// от новых к старым
+type OrderCursor struct {
+	DateCreated time.Time
+	OrderUID    string
+}
+
+// CursorOf возвращает курсор, указывающий на заказ
+func CursorOf(order Order) OrderCursor {
+	return OrderCursor{DateCreated: order.DateCreated, OrderUID: order.OrderUID}
+}
+
+// Encode кодирует курсор в непрозрачную строку для передачи клиенту
+func (c OrderCursor) Encode() string {
+	raw := c.DateCreated.UTC().Format(time.RFC3339Nano) + "|" + c.OrderUID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseOrderCursor разбирает курсор, полученный из Encode
+func ParseOrderCursor(s string) (OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return OrderCursor{}, ErrInvalidCursor
+	}
+	ts, uid, ok := strings.Cut(string(raw), "|")
+	if !ok || uid == "" {
+		return OrderCursor{}, ErrInvalidCursor
+	}
+	created, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return OrderCursor{}, ErrInvalidCursor
+	}
+	return OrderCursor{DateCreated: created, OrderUID: uid}, nil
+}
+
+// OrderPage — страница списка заказов
+type OrderPage struct {
+	Orders []Order `json:"orders"`
+	// NextCursor — курсор следующей страницы; пусто, если страниц больше нет
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOrderCursorRoundTrip(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	tests := []struct {
+		name   string
+		cursor OrderCursor
+	}{
+		{"UTC", OrderCursor{DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC), OrderUID: "b563feb7b2b84b6test"}},
+		{"наносекунды", OrderCursor{DateCreated: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC), OrderUID: "a"}},
+		{"другой часовой пояс", OrderCursor{DateCreated: time.Date(2024, 1, 2, 3, 4, 5, 0, moscow), OrderUID: "a"}},
+		{"разделитель в UID", OrderCursor{DateCreated: time.Unix(0, 0), OrderUID: "a|b|c"}},
+		{"не-ASCII UID", OrderCursor{DateCreated: time.Unix(1700000000, 0), OrderUID: "заказ-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := tt.cursor.Encode()
+			got, err := ParseOrderCursor(encoded)
+			if err != nil {
+				t.Fatalf("ParseOrderCursor(%q): %v", encoded, err)
+			}
+			if !got.DateCreated.Equal(tt.cursor.DateCreated) || got.OrderUID != tt.cursor.OrderUID {
+				t.Errorf("получен курсор %+v, ожидался %+v", got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestParseOrderCursorRejectsMalformed(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"пустая строка", ""},
+		{"не base64", "not a cursor!"},
+		{"base64 с дополнением", base64.URLEncoding.EncodeToString([]byte("2024-01-02T03:04:05Z|a"))},
+		{"нет разделителя", encode("2024-01-02T03:04:05Z")},
+		{"пустой UID", encode("2024-01-02T03:04:05Z|")},
+		{"некорректное время", encode("вчера|a")},
+		{"дата без времени", encode("2024-01-02|a")},
+		{"пустое время", encode("|a")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOrderCursor(tt.cursor)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("ParseOrderCursor(%q) = %+v, %v; ожидалась ErrInvalidCursor", tt.cursor, got, err)
+			}
+		})
+	}
+}
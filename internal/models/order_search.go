@@ -0,0 +1,11 @@
+package models
+
+// OrderSearchResult — заказ, найденный полнотекстовым поиском
+type OrderSearchResult struct {
+	Order Order `json:"order"`
+	// Rank — релевантность: сумма ts_rank по данным доставки и товарам заказа
+	Rank float64 `json:"rank"`
+	// Highlight — фрагменты доставки и товаров, где совпадения обрамлены <mark> и </mark>.
+	// Остальной текст не экранирован: перед вставкой в HTML его нужно экранировать.
+	Highlight string `json:"highlight"`
+}
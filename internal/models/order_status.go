@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// OrderStatus — статус заказа в его жизненном цикле
+type OrderStatus string
+
+const (
+	StatusCreated    OrderStatus = "created"
+	StatusPaid       OrderStatus = "paid"
+	StatusAssembling OrderStatus = "assembling"
+	StatusShipped    OrderStatus = "shipped"
+	StatusDelivered  OrderStatus = "delivered"
+	StatusCancelled  OrderStatus = "cancelled"
+	StatusReturned   OrderStatus = "returned"
+)
+
+// Valid сообщает, является ли значение известным статусом заказа
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case StatusCreated, StatusPaid, StatusAssembling, StatusShipped,
+		StatusDelivered, StatusCancelled, StatusReturned:
+		return true
+	default:
+		return false
+	}
+}
+
+// StatusChange — событие изменения статуса заказа из топика статусов
+type StatusChange struct {
+	OrderUID string      `json:"order_uid" validate:"required"`
+	Status   OrderStatus `json:"status" validate:"required"`
+	// ChangedAt — момент изменения у источника события. Обязателен: по статусу
+	// и ChangedAt распознаётся повторная доставка уже применённого события
+	ChangedAt time.Time `json:"changed_at" validate:"required"`
+	Reason    string    `json:"reason"`
+}
+
+// StatusHistoryEntry — запись истории статусов заказа
+type StatusHistoryEntry struct {
+	// From — предыдущий статус; пусто у начальной записи created
+	From      OrderStatus `json:"from_status,omitempty"`
+	To        OrderStatus `json:"to_status"`
+	ChangedAt time.Time   `json:"changed_at"`
+	Reason    string      `json:"reason,omitempty"`
+}
@@ -0,0 +1,212 @@
+// Package resp — минимальный клиент протокола RESP (Redis serialization protocol).
+// Поддерживается только то, что нужно внешнему кэшу заказов: команды с
+// аргументами-строками, конвейерная отправка и пул соединений.
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrClosed — клиент закрыт
+var ErrClosed = errors.New("resp: клиент закрыт")
+
+// Options — параметры подключения
+type Options struct {
+	// Password — пароль для AUTH (пусто — без авторизации)
+	Password string
+	// DB — номер базы для SELECT
+	DB int
+	// PoolSize — сколько простаивающих соединений держать (по умолчанию 8)
+	PoolSize int
+	// DialTimeout — таймаут установки соединения (по умолчанию 2s)
+	DialTimeout time.Duration
+	// IOTimeout — таймаут команды, если в контексте нет дедлайна (по умолчанию 1s)
+	IOTimeout time.Duration
+}
+
+// Client — потокобезопасный клиент с пулом соединений
+type Client struct {
+	addr string
+	opts Options
+	idle chan *conn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+type conn struct {
+	nc net.Conn
+	br *bufio.Reader
+	bw *bufio.Writer
+}
+
+// NewClient создает клиент. Соединения устанавливаются при первой команде.
+func NewClient(addr string, opts Options) *Client {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 8
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 2 * time.Second
+	}
+	if opts.IOTimeout <= 0 {
+		opts.IOTimeout = time.Second
+	}
+	return &Client{
+		addr: addr,
+		opts: opts,
+		idle: make(chan *conn, opts.PoolSize),
+	}
+}
+
+// Do выполняет одну команду
+func (c *Client) Do(ctx context.Context, args ...string) (Value, error) {
+	replies, err := c.Pipeline(ctx, args)
+	if err != nil {
+		return Value{}, err
+	}
+	return replies[0], replies[0].Err()
+}
+
+// Pipeline отправляет команды одним пакетом и читает ответы по порядку.
+// Ошибки уровня сервера (-ERR) возвращаются внутри Value, а не как error.
+func (c *Client) Pipeline(ctx context.Context, cmds ...[]string) ([]Value, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.opts.IOTimeout)
+	}
+	if err := cn.nc.SetDeadline(deadline); err != nil {
+		cn.nc.Close()
+		return nil, err
+	}
+
+	for _, args := range cmds {
+		writeCommand(cn.bw, args)
+	}
+	if err := cn.bw.Flush(); err != nil {
+		cn.nc.Close()
+		return nil, err
+	}
+
+	replies := make([]Value, len(cmds))
+	for i := range replies {
+		if replies[i], err = readValue(cn.br); err != nil {
+			// Поток ответов рассинхронизирован — соединение больше не годится
+			cn.nc.Close()
+			return nil, err
+		}
+	}
+
+	c.put(cn)
+	return replies, nil
+}
+
+// Ping проверяет доступность сервера
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Close закрывает все простаивающие соединения
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.idle)
+	for cn := range c.idle {
+		cn.nc.Close()
+	}
+	return nil
+}
+
+// get берёт соединение из пула или устанавливает новое
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn, ok := <-c.idle:
+		if !ok {
+			return nil, ErrClosed
+		}
+		return cn, nil
+	default:
+	}
+
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+
+	return c.dial(ctx)
+}
+
+// put возвращает соединение в пул; лишние соединения закрываются
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		cn.nc.Close()
+		return
+	}
+	select {
+	case c.idle <- cn:
+	default:
+		cn.nc.Close()
+	}
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	d := net.Dialer{Timeout: c.opts.DialTimeout}
+	nc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{nc: nc, br: bufio.NewReader(nc), bw: bufio.NewWriter(nc)}
+
+	var setup [][]string
+	if c.opts.Password != "" {
+		setup = append(setup, []string{"AUTH", c.opts.Password})
+	}
+	if c.opts.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.opts.DB)})
+	}
+	if len(setup) == 0 {
+		return cn, nil
+	}
+
+	if err := nc.SetDeadline(time.Now().Add(c.opts.IOTimeout)); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	for _, args := range setup {
+		writeCommand(cn.bw, args)
+	}
+	if err := cn.bw.Flush(); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	for range setup {
+		v, err := readValue(cn.br)
+		if err == nil {
+			err = v.Err()
+		}
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
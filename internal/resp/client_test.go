@@ -0,0 +1,265 @@
+package resp_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/highdolen/L0/internal/resp"
+	"github.com/highdolen/L0/internal/resp/resptest"
+)
+
+func newServer(t *testing.T, password string) *resptest.Server {
+	t.Helper()
+	srv, err := resptest.NewServerWithPassword(password)
+	if err != nil {
+		t.Fatalf("запуск RESP-сервера: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func newClient(t *testing.T, addr string, opts resp.Options) *resp.Client {
+	t.Helper()
+	c := resp.NewClient(addr, opts)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClientPipeline(t *testing.T) {
+	srv := newServer(t, "")
+	c := newClient(t, srv.Addr(), resp.Options{})
+	ctx := context.Background()
+
+	replies, err := c.Pipeline(ctx,
+		[]string{"SET", "a", "1"},
+		[]string{"SET", "b", "2"},
+		[]string{"GET", "b"},
+		[]string{"GET", "a"},
+		[]string{"GET", "missing"},
+		[]string{"DEL", "a", "missing"},
+	)
+	if err != nil {
+		t.Fatalf("Pipeline: %v", err)
+	}
+
+	want := []resp.Value{
+		{Kind: resp.SimpleString, Str: "OK"},
+		{Kind: resp.SimpleString, Str: "OK"},
+		{Kind: resp.BulkString, Str: "2"},
+		{Kind: resp.BulkString, Str: "1"},
+		{Kind: resp.BulkString, Null: true},
+		{Kind: resp.Integer, Int: 1},
+	}
+	if len(replies) != len(want) {
+		t.Fatalf("получено %d ответов, ожидалось %d", len(replies), len(want))
+	}
+	for i := range want {
+		if got := replies[i]; got.Kind != want[i].Kind || got.Str != want[i].Str || got.Int != want[i].Int || got.Null != want[i].Null {
+			t.Errorf("ответ %d = %+v, ожидался %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestClientConcurrentPipelines(t *testing.T) {
+	srv := newServer(t, "")
+	c := newClient(t, srv.Addr(), resp.Options{PoolSize: 2})
+	ctx := context.Background()
+
+	// Ответы каждого конвейера должны относиться к его собственным командам,
+	// даже когда соединения пула переиспользуются разными горутинами
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				key := "k" + strconv.Itoa(g) + ":" + strconv.Itoa(i)
+				replies, err := c.Pipeline(ctx, []string{"SET", key, key}, []string{"GET", key})
+				if err != nil {
+					t.Errorf("Pipeline: %v", err)
+					return
+				}
+				if replies[1].Str != key {
+					t.Errorf("GET %s вернул %q", key, replies[1].Str)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestClientAuthAndSelect(t *testing.T) {
+	srv := newServer(t, "secret")
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		opts    resp.Options
+		wantErr string // пусто — команда выполняется
+	}{
+		{"AUTH и SELECT", resp.Options{Password: "secret", DB: 3}, ""},
+		{"только AUTH", resp.Options{Password: "secret"}, ""},
+		{"неверный пароль", resp.Options{Password: "wrong", DB: 3}, "WRONGPASS"},
+		{"без пароля", resp.Options{}, "NOAUTH"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClient(t, srv.Addr(), tt.opts)
+			// Два вызова подряд: ответы на AUTH и SELECT не должны остаться
+			// в потоке и сдвинуть ответы на команды
+			for i := 0; i < 2; i++ {
+				_, err := c.Do(ctx, "SET", "key", "value")
+				switch {
+				case tt.wantErr == "" && err != nil:
+					t.Fatalf("SET: %v", err)
+				case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+					t.Fatalf("SET вернул %v, ожидалась ошибка %s", err, tt.wantErr)
+				}
+			}
+			if tt.wantErr == "" {
+				v, err := c.Do(ctx, "GET", "key")
+				if err != nil || v.Str != "value" {
+					t.Fatalf("GET = %q, %v", v.Str, err)
+				}
+			}
+		})
+	}
+}
+
+func TestClientErrorReply(t *testing.T) {
+	srv := newServer(t, "")
+	c := newClient(t, srv.Addr(), resp.Options{})
+	ctx := context.Background()
+
+	_, err := c.Do(ctx, "NOSUCHCOMMAND")
+	var respErr resp.Error
+	if !errors.As(err, &respErr) || !strings.HasPrefix(string(respErr), "ERR unknown command") {
+		t.Fatalf("Do вернул %v, ожидалась resp.Error с ERR unknown command", err)
+	}
+
+	// В конвейере ошибка одной команды возвращается в её Value и не мешает остальным
+	replies, err := c.Pipeline(ctx, []string{"SET", "a", "1", "EX", "0"}, []string{"SET", "a", "1"})
+	if err != nil {
+		t.Fatalf("Pipeline: %v", err)
+	}
+	if replies[0].Err() == nil {
+		t.Errorf("SET с EX 0 выполнен без ошибки")
+	}
+	if replies[1].Err() != nil {
+		t.Errorf("SET после ошибки: %v", replies[1].Err())
+	}
+
+	// Соединение после -ERR остаётся пригодным
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping после -ERR: %v", err)
+	}
+}
+
+// rawServer принимает соединения и на каждую команду отвечает reply(номер соединения).
+// Возвращает адрес и счётчик принятых соединений.
+func rawServer(t *testing.T, reply func(conn int) string) (string, *atomic.Int32) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var conns atomic.Int32
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			n := int(conns.Add(1))
+			go func() {
+				defer nc.Close()
+				br := bufio.NewReader(nc)
+				for {
+					if _, err := resp.ReadValue(br); err != nil {
+						return
+					}
+					if _, err := nc.Write([]byte(reply(n))); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), &conns
+}
+
+func TestClientClosesBrokenConn(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+	}{
+		{"неизвестный тип", "?what\r\n"},
+		{"строка без CRLF", "+OK\n"},
+		{"длина больше maxBulkLen", "$" + strconv.Itoa(512<<20+1) + "\r\n"},
+		{"массив больше maxBulkLen", "*" + strconv.Itoa(512<<20+1) + "\r\n"},
+		{"некорректная длина", "$abc\r\n"},
+		{"bulk-строка без CRLF", "$2\r\nOKxx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Первое соединение отвечает мусором, следующие — корректно
+			addr, conns := rawServer(t, func(conn int) string {
+				if conn == 1 {
+					return tt.reply
+				}
+				return "+PONG\r\n"
+			})
+			c := newClient(t, addr, resp.Options{})
+			ctx := context.Background()
+
+			if err := c.Ping(ctx); err == nil {
+				t.Fatalf("Ping с ответом %q выполнен без ошибки", tt.reply)
+			}
+			// Рассинхронизированное соединение не возвращается в пул:
+			// следующая команда идёт по новому соединению
+			if err := c.Ping(ctx); err != nil {
+				t.Fatalf("Ping после сбоя: %v", err)
+			}
+			if n := conns.Load(); n != 2 {
+				t.Errorf("соединений: %d, ожидалось 2", n)
+			}
+		})
+	}
+}
+
+func TestClientReusesConn(t *testing.T) {
+	addr, conns := rawServer(t, func(int) string { return "+PONG\r\n" })
+	c := newClient(t, addr, resp.Options{})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := c.Ping(ctx); err != nil {
+			t.Fatalf("Ping: %v", err)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("соединений: %d, ожидалось 1", n)
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	srv := newServer(t, "")
+	c := resp.NewClient(srv.Addr(), resp.Options{})
+	c.Close()
+
+	if err := c.Ping(context.Background()); !errors.Is(err, resp.ErrClosed) {
+		t.Fatalf("Ping после Close вернул %v, ожидалась ErrClosed", err)
+	}
+}
@@ -0,0 +1,186 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Kind — тип значения RESP
+type Kind byte
+
+const (
+	SimpleString Kind = '+'
+	ErrorReply   Kind = '-'
+	Integer      Kind = ':'
+	BulkString   Kind = '$'
+	Array        Kind = '*'
+)
+
+// maxBulkLen ограничивает размер строки и массива в ответе, чтобы
+// повреждённый поток не привёл к огромной аллокации
+const maxBulkLen = 512 << 20
+
+// Error — ошибка, которую вернул сервер (-ERR ...)
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Value — значение RESP
+type Value struct {
+	Kind  Kind
+	Str   string
+	Int   int64
+	Array []Value
+	// Null — nil bulk string или nil array
+	Null bool
+}
+
+// Err возвращает ошибку, если значение — ответ -ERR
+func (v Value) Err() error {
+	if v.Kind == ErrorReply {
+		return Error(v.Str)
+	}
+	return nil
+}
+
+// Bytes возвращает содержимое строки
+func (v Value) Bytes() []byte {
+	return []byte(v.Str)
+}
+
+// writeCommand записывает команду как массив bulk-строк
+func writeCommand(w *bufio.Writer, args []string) {
+	w.WriteByte('*')
+	w.WriteString(strconv.Itoa(len(args)))
+	w.WriteString("\r\n")
+	for _, arg := range args {
+		WriteBulk(w, arg)
+	}
+}
+
+// WriteBulk записывает bulk-строку
+func WriteBulk(w *bufio.Writer, s string) {
+	w.WriteByte('$')
+	w.WriteString(strconv.Itoa(len(s)))
+	w.WriteString("\r\n")
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// WriteValue записывает произвольное значение RESP
+func WriteValue(w *bufio.Writer, v Value) {
+	switch v.Kind {
+	case SimpleString, ErrorReply:
+		w.WriteByte(byte(v.Kind))
+		w.WriteString(v.Str)
+		w.WriteString("\r\n")
+	case Integer:
+		w.WriteByte(':')
+		w.WriteString(strconv.FormatInt(v.Int, 10))
+		w.WriteString("\r\n")
+	case BulkString:
+		if v.Null {
+			w.WriteString("$-1\r\n")
+			return
+		}
+		WriteBulk(w, v.Str)
+	case Array:
+		if v.Null {
+			w.WriteString("*-1\r\n")
+			return
+		}
+		w.WriteByte('*')
+		w.WriteString(strconv.Itoa(len(v.Array)))
+		w.WriteString("\r\n")
+		for _, item := range v.Array {
+			WriteValue(w, item)
+		}
+	}
+}
+
+// ReadValue читает одно значение RESP
+func ReadValue(r *bufio.Reader) (Value, error) {
+	return readValue(r)
+}
+
+func readValue(r *bufio.Reader) (Value, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return Value{}, err
+	}
+	if len(line) == 0 {
+		return Value{}, errors.New("resp: пустая строка протокола")
+	}
+
+	kind, payload := Kind(line[0]), line[1:]
+	switch kind {
+	case SimpleString, ErrorReply:
+		return Value{Kind: kind, Str: payload}, nil
+
+	case Integer:
+		n, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("resp: некорректное число %q", payload)
+		}
+		return Value{Kind: Integer, Int: n}, nil
+
+	case BulkString:
+		n, err := parseLen(payload)
+		if err != nil {
+			return Value{}, err
+		}
+		if n < 0 {
+			return Value{Kind: BulkString, Null: true}, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return Value{}, err
+		}
+		if buf[n] != '\r' || buf[n+1] != '\n' {
+			return Value{}, errors.New("resp: bulk-строка без завершающего CRLF")
+		}
+		return Value{Kind: BulkString, Str: string(buf[:n])}, nil
+
+	case Array:
+		n, err := parseLen(payload)
+		if err != nil {
+			return Value{}, err
+		}
+		if n < 0 {
+			return Value{Kind: Array, Null: true}, nil
+		}
+		items := make([]Value, n)
+		for i := range items {
+			if items[i], err = readValue(r); err != nil {
+				return Value{}, err
+			}
+		}
+		return Value{Kind: Array, Array: items}, nil
+
+	default:
+		return Value{}, fmt.Errorf("resp: неизвестный тип %q", line[0])
+	}
+}
+
+func parseLen(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < -1 || n > maxBulkLen {
+		return 0, fmt.Errorf("resp: некорректная длина %q", s)
+	}
+	return n, nil
+}
+
+// readLine читает строку до CRLF без разделителя
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", errors.New("resp: строка без CRLF")
+	}
+	return line[:len(line)-2], nil
+}
@@ -0,0 +1,400 @@
+// Package resptest — встраиваемый в процесс RESP-сервер для проверки кода,
+// работающего с внешним кэшем, без настоящего Redis. Поддерживает только
+// команды, которые использует кэш заказов: PING, AUTH, SELECT, GET, MGET,
+// SET (EX/PX/NX/XX), DEL, EXISTS, PEXPIRE, PTTL, SCAN, DBSIZE, FLUSHDB.
+package resptest
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/highdolen/L0/internal/resp"
+)
+
+type item struct {
+	value    string
+	expireAt time.Time // нулевое значение — без срока жизни
+	seq      int       // порядковый номер создания ключа — курсор SCAN
+}
+
+// Server — RESP-сервер с хранилищем в памяти
+type Server struct {
+	ln       net.Listener
+	password string
+
+	mu    sync.Mutex
+	data  map[string]item
+	seq   int
+	now   func() time.Time
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// NewServer запускает сервер на случайном локальном порту
+func NewServer() (*Server, error) {
+	return NewServerWithPassword("")
+}
+
+// NewServerWithPassword запускает сервер, требующий AUTH с указанным паролем
+func NewServerWithPassword(password string) (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ln:       ln,
+		password: password,
+		data:     make(map[string]item),
+		now:      time.Now,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr — адрес сервера для resp.NewClient
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// SetClock подменяет источник времени, чтобы проверять истечение TTL без ожидания
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// Keys возвращает все не истёкшие ключи в отсортированном порядке
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		if _, ok := s.lookup(k); ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Close останавливает сервер и закрывает все соединения
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(c)
+	}
+}
+
+func (s *Server) handle(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+
+	br := bufio.NewReader(c)
+	bw := bufio.NewWriter(c)
+	authed := s.password == ""
+	for {
+		cmd, err := resp.ReadValue(br)
+		if err != nil {
+			return
+		}
+		args, err := commandArgs(cmd)
+		var reply resp.Value
+		switch {
+		case err != nil:
+			reply = errorReply(err.Error())
+		case strings.EqualFold(args[0], "AUTH"):
+			if len(args) == 2 && args[1] == s.password {
+				authed = true
+				reply = ok()
+			} else {
+				reply = errorReply("WRONGPASS invalid password")
+			}
+		case !authed:
+			reply = errorReply("NOAUTH Authentication required.")
+		default:
+			reply = s.exec(args)
+		}
+
+		resp.WriteValue(bw, reply)
+		// Ответы конвейера отправляются пачкой, когда входящие команды закончились
+		if br.Buffered() == 0 {
+			if err := bw.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func commandArgs(v resp.Value) ([]string, error) {
+	if v.Kind != resp.Array || len(v.Array) == 0 {
+		return nil, errors.New("ERR protocol error: expected array")
+	}
+	args := make([]string, len(v.Array))
+	for i, a := range v.Array {
+		if a.Kind != resp.BulkString || a.Null {
+			return nil, errors.New("ERR protocol error: expected bulk string")
+		}
+		args[i] = a.Str
+	}
+	return args, nil
+}
+
+func (s *Server) exec(args []string) resp.Value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := strings.ToUpper(args[0])
+	args = args[1:]
+	switch name {
+	case "PING":
+		return resp.Value{Kind: resp.SimpleString, Str: "PONG"}
+	case "SELECT":
+		return ok()
+	case "GET":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		return s.get(args[0])
+	case "MGET":
+		if len(args) == 0 {
+			return wrongArgs(name)
+		}
+		values := make([]resp.Value, len(args))
+		for i, k := range args {
+			values[i] = s.get(k)
+		}
+		return resp.Value{Kind: resp.Array, Array: values}
+	case "SET":
+		return s.set(args)
+	case "DEL":
+		n := 0
+		for _, k := range args {
+			if _, ok := s.lookup(k); ok {
+				n++
+			}
+			delete(s.data, k)
+		}
+		return integer(n)
+	case "EXISTS":
+		n := 0
+		for _, k := range args {
+			if _, ok := s.lookup(k); ok {
+				n++
+			}
+		}
+		return integer(n)
+	case "PEXPIRE":
+		if len(args) != 2 {
+			return wrongArgs(name)
+		}
+		ms, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return errorReply("ERR value is not an integer or out of range")
+		}
+		it, ok := s.lookup(args[0])
+		if !ok {
+			return integer(0)
+		}
+		it.expireAt = s.now().Add(time.Duration(ms) * time.Millisecond)
+		s.data[args[0]] = it
+		return integer(1)
+	case "PTTL":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		it, ok := s.lookup(args[0])
+		switch {
+		case !ok:
+			return integer(-2)
+		case it.expireAt.IsZero():
+			return integer(-1)
+		default:
+			return integer(int(it.expireAt.Sub(s.now()).Milliseconds()))
+		}
+	case "SCAN":
+		return s.scan(args)
+	case "DBSIZE":
+		n := 0
+		for k := range s.data {
+			if _, ok := s.lookup(k); ok {
+				n++
+			}
+		}
+		return integer(n)
+	case "FLUSHDB":
+		s.data = make(map[string]item)
+		return ok()
+	default:
+		return errorReply("ERR unknown command '" + name + "'")
+	}
+}
+
+// lookup возвращает запись, удаляя её, если срок жизни истёк. Вызывается под s.mu.
+func (s *Server) lookup(key string) (item, bool) {
+	it, ok := s.data[key]
+	if !ok {
+		return item{}, false
+	}
+	if !it.expireAt.IsZero() && !s.now().Before(it.expireAt) {
+		delete(s.data, key)
+		return item{}, false
+	}
+	return it, true
+}
+
+func (s *Server) get(key string) resp.Value {
+	it, ok := s.lookup(key)
+	if !ok {
+		return resp.Value{Kind: resp.BulkString, Null: true}
+	}
+	return resp.Value{Kind: resp.BulkString, Str: it.value}
+}
+
+func (s *Server) set(args []string) resp.Value {
+	if len(args) < 2 {
+		return wrongArgs("SET")
+	}
+	key, it := args[0], item{value: args[1]}
+	var nx, xx bool
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return errorReply("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return errorReply("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Millisecond
+			if opt == "EX" {
+				unit = time.Second
+			}
+			it.expireAt = s.now().Add(time.Duration(n) * unit)
+			i++
+		default:
+			return errorReply("ERR syntax error")
+		}
+	}
+
+	old, exists := s.lookup(key)
+	if (nx && exists) || (xx && !exists) {
+		return resp.Value{Kind: resp.BulkString, Null: true}
+	}
+	if exists {
+		it.seq = old.seq
+	} else {
+		s.seq++
+		it.seq = s.seq
+	}
+	s.data[key] = it
+	return ok()
+}
+
+// scan обходит ключи в порядке создания; курсор — номер следующего ключа.
+// Как и в Redis, ключи, существовавшие всё время обхода, возвращаются
+// ровно один раз, даже если другие ключи удаляются между вызовами.
+func (s *Server) scan(args []string) resp.Value {
+	if len(args) < 1 {
+		return wrongArgs("SCAN")
+	}
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil || cursor < 0 {
+		return errorReply("ERR invalid cursor")
+	}
+	pattern, count := "*", 10
+	for i := 1; i+1 < len(args); i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			if count, err = strconv.Atoi(args[i+1]); err != nil || count < 1 {
+				return errorReply("ERR syntax error")
+			}
+		default:
+			return errorReply("ERR syntax error")
+		}
+	}
+
+	type seqKey struct {
+		key string
+		seq int
+	}
+	var keys []seqKey
+	for k := range s.data {
+		if it, ok := s.lookup(k); ok && it.seq >= cursor {
+			keys = append(keys, seqKey{k, it.seq})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].seq < keys[j].seq })
+
+	next := 0
+	if len(keys) > count {
+		next = keys[count].seq
+		keys = keys[:count]
+	}
+	var matched []resp.Value
+	for _, k := range keys {
+		if ok, _ := path.Match(pattern, k.key); ok {
+			matched = append(matched, resp.Value{Kind: resp.BulkString, Str: k.key})
+		}
+	}
+	return resp.Value{Kind: resp.Array, Array: []resp.Value{
+		{Kind: resp.BulkString, Str: strconv.Itoa(next)},
+		{Kind: resp.Array, Array: matched},
+	}}
+}
+
+func ok() resp.Value {
+	return resp.Value{Kind: resp.SimpleString, Str: "OK"}
+}
+
+func integer(n int) resp.Value {
+	return resp.Value{Kind: resp.Integer, Int: int64(n)}
+}
+
+func errorReply(msg string) resp.Value {
+	return resp.Value{Kind: resp.ErrorReply, Str: msg}
+}
+
+func wrongArgs(name string) resp.Value {
+	return errorReply("ERR wrong number of arguments for '" + strings.ToLower(name) + "' command")
+}
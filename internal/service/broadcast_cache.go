@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/highdolen/L0/internal/cache"
+	"github.com/highdolen/L0/internal/models"
+)
+
+// broadcastTimeout ограничивает отправку события инвалидации
+const broadcastTimeout = 5 * time.Second
+
+// InvalidationBroadcaster рассылает инвалидации кеша другим репликам сервиса
+type InvalidationBroadcaster interface {
+	// Broadcast сообщает об удалении заказа uid из кеша (пустой uid — полная очистка)
+	Broadcast(ctx context.Context, uid string) error
+	// BroadcastIndexed сообщает об удалении заказа uid (может быть пустым) и заказов
+	// с заданными значениями вторичных индексов
+	BroadcastIndexed(ctx context.Context, uid string, indexes map[cache.Index]string) error
+}
+
+// broadcastingCache — кеш, который после локального удаления рассылает
+// инвалидацию остальным репликам. Полученные от других реплик события
+// применяются к исходному кешу напрямую, поэтому повторно не рассылаются.
+type broadcastingCache struct {
+	CacheService
+	broadcaster InvalidationBroadcaster
+}
+
+// NewBroadcastingCache оборачивает кеш рассылкой инвалидаций
+func NewBroadcastingCache(cache CacheService, broadcaster InvalidationBroadcaster) CacheService {
+	return &broadcastingCache{
+		CacheService: cache,
+		broadcaster:  broadcaster,
+	}
+}
+
+// Delete удаляет заказ из кеша и сообщает об этом другим репликам
+func (c *broadcastingCache) Delete(uid string) {
+	c.CacheService.Delete(uid)
+	c.broadcast(uid)
+}
+
+// InvalidateAll очищает кеш и сообщает об этом другим репликам
+func (c *broadcastingCache) InvalidateAll() {
+	c.CacheService.InvalidateAll()
+	c.broadcast("")
+}
+
+// DeleteIndexed удаляет заказы по вторичному индексу и сообщает об этом другим
+// репликам: у них удаляются заказы с тем же значением индекса, даже если их
+// набор отличается от локального
+func (c *broadcastingCache) DeleteIndexed(idx cache.Index, value string) []string {
+	removed := c.CacheService.DeleteIndexed(idx, value)
+	broadcastIndexed(c.broadcaster, "", map[cache.Index]string{idx: value})
+	return removed
+}
+
+// broadcast отправляет событие; ошибка только логируется — локальный кеш уже
+// инвалидирован, а на других репликах запись истечёт по TTL
+func (c *broadcastingCache) broadcast(uid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), broadcastTimeout)
+	defer cancel()
+	if err := c.broadcaster.Broadcast(ctx, uid); err != nil {
+		log.Printf("Ошибка рассылки инвалидации кеша %q: %v", uid, err)
+	}
+}
+
+// broadcastIndexed отправляет событие об удалении по вторичным индексам; ошибка только логируется
+func broadcastIndexed(broadcaster InvalidationBroadcaster, uid string, indexes map[cache.Index]string) {
+	ctx, cancel := context.WithTimeout(context.Background(), broadcastTimeout)
+	defer cancel()
+	if err := broadcaster.BroadcastIndexed(ctx, uid, indexes); err != nil {
+		log.Printf("Ошибка рассылки инвалидации кеша %q %v: %v", uid, indexes, err)
+	}
+}
+
+// IngestCache — кеш, в который Kafka consumer кладёт новые заказы.
+// Заказ сохраняется в локальный кеш, а другим репликам рассылается его
+// инвалидация: они удаляют негативную запись по UID и заказы с тем же
+// покупателем и track_number, чтобы их наборы перестали считаться полными
+// и следующий поиск по индексу увидел новый заказ.
+type IngestCache struct {
+	cache       CacheService
+	broadcaster InvalidationBroadcaster
+}
+
+// NewIngestCache создает кеш для новых заказов из Kafka
+func NewIngestCache(cache CacheService, broadcaster InvalidationBroadcaster) *IngestCache {
+	return &IngestCache{
+		cache:       cache,
+		broadcaster: broadcaster,
+	}
+}
+
+// Set сохраняет новый заказ и сообщает о нём другим репликам
+func (c *IngestCache) Set(uid string, order models.Order) {
+	c.cache.Set(uid, order)
+
+	indexes := make(map[cache.Index]string, 2)
+	for _, idx := range []cache.Index{cache.ByCustomer, cache.ByTrackNumber} {
+		if value := idx.Value(order); value != "" {
+			indexes[idx] = value
+		}
+	}
+	broadcastIndexed(c.broadcaster, uid, indexes)
+}
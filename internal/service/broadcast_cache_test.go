@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/highdolen/L0/internal/cache"
+)
+
+// broadcast — событие, отправленное recordingBroadcaster
+type broadcast struct {
+	uid     string
+	indexes map[cache.Index]string
+}
+
+type recordingBroadcaster struct {
+	events []broadcast
+}
+
+func (b *recordingBroadcaster) Broadcast(ctx context.Context, uid string) error {
+	b.events = append(b.events, broadcast{uid: uid})
+	return nil
+}
+
+func (b *recordingBroadcaster) BroadcastIndexed(ctx context.Context, uid string, indexes map[cache.Index]string) error {
+	b.events = append(b.events, broadcast{uid: uid, indexes: indexes})
+	return nil
+}
+
+func TestBroadcasts(t *testing.T) {
+	order := testOrder("a", "TRACK")
+
+	tests := []struct {
+		name string
+		run  func(c CacheService, b InvalidationBroadcaster)
+		want []broadcast
+	}{
+		{
+			name: "новый заказ из Kafka",
+			run: func(c CacheService, b InvalidationBroadcaster) {
+				NewIngestCache(c, b).Set("a", order)
+			},
+			want: []broadcast{{uid: "a", indexes: map[cache.Index]string{cache.ByCustomer: "customer", cache.ByTrackNumber: "TRACK"}}},
+		},
+		{
+			name: "заказ без track_number",
+			run: func(c CacheService, b InvalidationBroadcaster) {
+				o := order
+				o.TrackNumber = ""
+				NewIngestCache(c, b).Set("a", o)
+			},
+			want: []broadcast{{uid: "a", indexes: map[cache.Index]string{cache.ByCustomer: "customer"}}},
+		},
+		{
+			name: "удаление по индексу",
+			run: func(c CacheService, b InvalidationBroadcaster) {
+				c.Set("a", order)
+				NewBroadcastingCache(c, b).DeleteIndexed(cache.ByCustomer, "customer")
+			},
+			want: []broadcast{{indexes: map[cache.Index]string{cache.ByCustomer: "customer"}}},
+		},
+		{
+			name: "удаление заказа",
+			run: func(c CacheService, b InvalidationBroadcaster) {
+				NewBroadcastingCache(c, b).Delete("a")
+			},
+			want: []broadcast{{uid: "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cache.New(time.Hour)
+			defer c.Close()
+			b := &recordingBroadcaster{}
+
+			tt.run(NewCacheAdapter(c), b)
+
+			if len(b.events) != len(tt.want) {
+				t.Fatalf("разослано %+v, ожидалось %+v", b.events, tt.want)
+			}
+			for i, want := range tt.want {
+				got := b.events[i]
+				if got.uid != want.uid || !maps.Equal(got.indexes, want.indexes) {
+					t.Errorf("событие %d = %+v, ожидалось %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIngestCacheStoresLocally(t *testing.T) {
+	c := cache.New(time.Hour)
+	defer c.Close()
+
+	NewIngestCache(NewCacheAdapter(c), &recordingBroadcaster{}).Set("a", testOrder("a", "TRACK"))
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("заказ не сохранён в локальный кеш")
+	}
+}
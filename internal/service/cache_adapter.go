@@ -1,13 +1,39 @@
 package service
 
 import (
+	"io"
+	"time"
+
 	"github.com/highdolen/L0/internal/cache"
 	"github.com/highdolen/L0/internal/models"
 )
 
+// orderCache — общие методы реализаций кеша (OrderCache, ShardedCache и RedisCache)
+type orderCache interface {
+	Get(uid string) (models.Order, bool)
+	Lookup(uid string) (models.Order, cache.LookupStatus)
+	Set(uid string, order models.Order)
+	SetWithTTL(uid string, order models.Order, ttl time.Duration)
+	SetNotFound(uid string)
+	Delete(uid string)
+	InvalidateAll()
+	LookupIndex(idx cache.Index, value string) ([]models.Order, bool)
+	SetIndexed(idx cache.Index, value string, orders []models.Order)
+	DeleteIndexed(idx cache.Index, value string) []string
+	Keys(prefix, after string, limit int) ([]string, string)
+	Entry(uid string) (cache.EntryInfo, bool)
+	GetStats() cache.CacheStats
+	Subscribe(buffer int) *cache.Subscription
+	SaveSnapshot(w io.Writer) error
+	LoadSnapshot(r io.Reader) (time.Time, error)
+	Close()
+}
+
 // cacheAdapter адаптирует существующий кеш к интерфейсу CacheService
 type cacheAdapter struct {
-	cache *cache.OrderCache
+	cache orderCache
+	// invalidations — инвалидации, сделанные во время фонового заполнения кеша
+	invalidations invalidationLog
 }
 
 // NewCacheAdapter создает новый адаптер для кеша
@@ -17,31 +43,110 @@ func NewCacheAdapter(cache *cache.OrderCache) CacheService {
 	}
 }
 
+// NewShardedCacheAdapter создает адаптер для шардированного кеша
+func NewShardedCacheAdapter(cache *cache.ShardedCache) CacheService {
+	return &cacheAdapter{
+		cache: cache,
+	}
+}
+
+// NewRedisCacheAdapter создает адаптер для внешнего кеша, общего для всех реплик
+func NewRedisCacheAdapter(cache *cache.RedisCache) CacheService {
+	return &cacheAdapter{
+		cache: cache,
+	}
+}
+
 // Get получает заказ из кеша
 func (a *cacheAdapter) Get(uid string) (models.Order, bool) {
 	return a.cache.Get(uid)
 }
 
+// Lookup получает заказ из кеша вместе с состоянием записи
+func (a *cacheAdapter) Lookup(uid string) (models.Order, cache.LookupStatus) {
+	return a.cache.Lookup(uid)
+}
+
 // Set сохраняет заказ в кеш
 func (a *cacheAdapter) Set(uid string, order models.Order) {
 	a.cache.Set(uid, order)
 }
 
+// SetWithTTL сохраняет заказ в кеш с собственным временем жизни
+func (a *cacheAdapter) SetWithTTL(uid string, order models.Order, ttl time.Duration) {
+	a.cache.SetWithTTL(uid, order, ttl)
+}
+
+// SetNotFound запоминает, что заказа нет в базе данных
+func (a *cacheAdapter) SetNotFound(uid string) {
+	a.cache.SetNotFound(uid)
+}
+
 // Delete удаляет заказ из кеша
 func (a *cacheAdapter) Delete(uid string) {
-	a.cache.Delete(uid)
+	a.invalidations.invalidate(func() []string {
+		a.cache.Delete(uid)
+		return []string{uid}
+	})
 }
 
 // InvalidateAll очищает весь кеш
 func (a *cacheAdapter) InvalidateAll() {
-	a.cache.InvalidateAll()
+	a.invalidations.invalidateAll(a.cache.InvalidateAll)
+}
+
+// LookupIndex получает из кеша заказы по вторичному индексу
+func (a *cacheAdapter) LookupIndex(idx cache.Index, value string) ([]models.Order, bool) {
+	return a.cache.LookupIndex(idx, value)
+}
+
+// SetIndexed сохраняет в кеш полный набор заказов по вторичному индексу
+func (a *cacheAdapter) SetIndexed(idx cache.Index, value string, orders []models.Order) {
+	a.cache.SetIndexed(idx, value, orders)
+}
+
+// DeleteIndexed удаляет из кеша заказы с заданным track_number или customer_id
+func (a *cacheAdapter) DeleteIndexed(idx cache.Index, value string) []string {
+	return a.invalidations.invalidate(func() []string {
+		return a.cache.DeleteIndexed(idx, value)
+	})
+}
+
+// StartFill начинает фоновое заполнение кеша
+func (a *cacheAdapter) StartFill() CacheFill {
+	return &cacheFill{adapter: a, start: a.invalidations.begin()}
+}
+
+// Keys возвращает страницу UID заказов в кеше
+func (a *cacheAdapter) Keys(prefix, after string, limit int) ([]string, string) {
+	return a.cache.Keys(prefix, after, limit)
+}
+
+// Entry возвращает сведения о записи заказа в кеше
+func (a *cacheAdapter) Entry(uid string) (cache.EntryInfo, bool) {
+	return a.cache.Entry(uid)
 }
 
 // GetStats возвращает статистику кеша
-func (a *cacheAdapter) GetStats() map[string]interface{} {
+func (a *cacheAdapter) GetStats() cache.CacheStats {
 	return a.cache.GetStats()
 }
 
+// Subscribe подписывает на события кеша
+func (a *cacheAdapter) Subscribe(buffer int) *cache.Subscription {
+	return a.cache.Subscribe(buffer)
+}
+
+// SaveSnapshot записывает содержимое кеша в w
+func (a *cacheAdapter) SaveSnapshot(w io.Writer) error {
+	return a.cache.SaveSnapshot(w)
+}
+
+// LoadSnapshot загружает кеш из снимка
+func (a *cacheAdapter) LoadSnapshot(r io.Reader) (time.Time, error) {
+	return a.cache.LoadSnapshot(r)
+}
+
 // Close корректно завершает работу кеша
 func (a *cacheAdapter) Close() {
 	a.cache.Close()
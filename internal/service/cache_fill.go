@@ -0,0 +1,128 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// CacheFill — фоновое заполнение кеша (прогрев, восстановление из снимка и догрузка).
+// Заказы читаются из БД заранее, поэтому заказ, инвалидированный после чтения
+// (смена статуса, удаление через API или событие от другой реплики), не должен
+// вернуться в кеш со старыми данными. Заполнение записывает только заказы,
+// которые не инвалидировались с момента его начала.
+type CacheFill interface {
+	// Set кладёт заказ в кеш, если он не инвалидировался с начала заполнения;
+	// false — запись пропущена, заказ загрузится из БД при первом обращении
+	Set(uid string, order models.Order) bool
+
+	// Purge удаляет из кеша заказы, инвалидированные с начала заполнения.
+	// Нужен после записи в обход Set, например восстановления из снимка.
+	Purge()
+
+	// Done завершает заполнение; повторный вызов ничего не делает
+	Done()
+}
+
+// invalidationLog нумерует инвалидации кеша, пока идёт хотя бы одно заполнение.
+// Удаление из кеша и его регистрация, а также проверка и запись заполнения
+// выполняются под одним мьютексом, поэтому заполнение не может записать заказ
+// между удалением и регистрацией.
+type invalidationLog struct {
+	mu    sync.Mutex
+	gen   uint64
+	fills int
+	// keys — поколение последней инвалидации заказа
+	keys map[string]uint64
+	// all — поколение последней полной очистки кеша
+	all uint64
+}
+
+// begin регистрирует новое заполнение и возвращает текущее поколение
+func (l *invalidationLog) begin() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.fills == 0 {
+		l.keys = make(map[string]uint64)
+	}
+	l.fills++
+	return l.gen
+}
+
+// end снимает заполнение; когда активных заполнений нет, журнал очищается
+func (l *invalidationLog) end() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fills--
+	if l.fills == 0 {
+		l.keys = nil
+	}
+}
+
+// invalidate выполняет удаление del и регистрирует удалённые заказы
+func (l *invalidationLog) invalidate(del func() []string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	uids := del()
+	if l.fills > 0 {
+		l.gen++
+		for _, uid := range uids {
+			l.keys[uid] = l.gen
+		}
+	}
+	return uids
+}
+
+// invalidateAll выполняет полную очистку flush и регистрирует её
+func (l *invalidationLog) invalidateAll(flush func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	flush()
+	if l.fills > 0 {
+		l.gen++
+		l.all = l.gen
+	}
+}
+
+// invalidatedSince сообщает, инвалидировался ли заказ после поколения start.
+// Вызывается под l.mu.
+func (l *invalidationLog) invalidatedSince(uid string, start uint64) bool {
+	return l.all > start || l.keys[uid] > start
+}
+
+// cacheFill — заполнение кеша адаптера, начатое на поколении start
+type cacheFill struct {
+	adapter *cacheAdapter
+	start   uint64
+	once    sync.Once
+}
+
+func (f *cacheFill) Set(uid string, order models.Order) bool {
+	l := &f.adapter.invalidations
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.invalidatedSince(uid, f.start) {
+		return false
+	}
+	f.adapter.cache.Set(uid, order)
+	return true
+}
+
+func (f *cacheFill) Purge() {
+	l := &f.adapter.invalidations
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.all > f.start {
+		f.adapter.cache.InvalidateAll()
+		return
+	}
+	for uid, gen := range l.keys {
+		if gen > f.start {
+			f.adapter.cache.Delete(uid)
+		}
+	}
+}
+
+func (f *cacheFill) Done() {
+	f.once.Do(f.adapter.invalidations.end)
+}
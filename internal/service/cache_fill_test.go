@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/highdolen/L0/internal/cache"
+	"github.com/highdolen/L0/internal/models"
+)
+
+func TestCacheFill(t *testing.T) {
+	tests := []struct {
+		name string
+		// run выполняет сценарий заполнения над адаптером
+		run  func(a CacheService)
+		want map[string]bool // uid → заказ должен быть в кеше
+	}{
+		{
+			name: "без инвалидаций",
+			run: func(a CacheService) {
+				fill := a.StartFill()
+				defer fill.Done()
+				fill.Set("a", testOrder("a", "A"))
+			},
+			want: map[string]bool{"a": true},
+		},
+		{
+			name: "заказ инвалидирован после начала",
+			run: func(a CacheService) {
+				fill := a.StartFill()
+				defer fill.Done()
+				a.Delete("a")
+				fill.Set("a", testOrder("a", "A"))
+				fill.Set("b", testOrder("b", "B"))
+			},
+			want: map[string]bool{"a": false, "b": true},
+		},
+		{
+			name: "заказ инвалидирован до начала",
+			run: func(a CacheService) {
+				a.Delete("a")
+				fill := a.StartFill()
+				defer fill.Done()
+				fill.Set("a", testOrder("a", "A"))
+			},
+			want: map[string]bool{"a": true},
+		},
+		{
+			name: "полная очистка во время заполнения",
+			run: func(a CacheService) {
+				fill := a.StartFill()
+				defer fill.Done()
+				fill.Set("a", testOrder("a", "A"))
+				a.InvalidateAll()
+				fill.Set("b", testOrder("b", "B"))
+			},
+			want: map[string]bool{"a": false, "b": false},
+		},
+		{
+			name: "удаление по индексу",
+			run: func(a CacheService) {
+				fill := a.StartFill()
+				defer fill.Done()
+				a.Set("a", testOrder("a", "A"))
+				a.DeleteIndexed(cache.ByCustomer, "customer")
+				fill.Set("a", testOrder("a", "A"))
+			},
+			want: map[string]bool{"a": false},
+		},
+		{
+			name: "инвалидация видна только заполнению, начатому раньше",
+			run: func(a CacheService) {
+				older := a.StartFill()
+				defer older.Done()
+				a.Delete("a")
+				newer := a.StartFill()
+				defer newer.Done()
+				older.Set("a", testOrder("a", "OLD"))
+				newer.Set("b", testOrder("b", "B"))
+			},
+			want: map[string]bool{"a": false, "b": true},
+		},
+		{
+			name: "Purge убирает записанное в обход Set",
+			run: func(a CacheService) {
+				fill := a.StartFill()
+				defer fill.Done()
+				a.Delete("a")
+				// восстановление из снимка пишет напрямую в кеш
+				a.Set("a", testOrder("a", "STALE"))
+				a.Set("b", testOrder("b", "B"))
+				fill.Purge()
+			},
+			want: map[string]bool{"a": false, "b": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cache.New(time.Hour)
+			defer c.Close()
+			a := NewCacheAdapter(c)
+
+			tt.run(a)
+
+			for uid, want := range tt.want {
+				if _, ok := c.Get(uid); ok != want {
+					t.Errorf("заказ %s в кеше: %v, ожидалось %v", uid, ok, want)
+				}
+			}
+			// После завершения всех заполнений журнал инвалидаций не хранится
+			if keys := a.(*cacheAdapter).invalidations.keys; keys != nil {
+				t.Errorf("журнал инвалидаций не очищен: %v", keys)
+			}
+		})
+	}
+}
+
+// racingRepo отдаёт заказы, прочитанные до того, как onRead инвалидирует их
+type racingRepo struct {
+	OrderRepository
+	orders []models.Order
+	onRead func()
+}
+
+func (r *racingRepo) StreamOrders(ctx context.Context, fn func(models.Order) error) error {
+	r.onRead()
+	return streamAll(r.orders, fn)
+}
+
+func TestLoadFromDBSkipsInvalidatedDuringLoad(t *testing.T) {
+	c := cache.New(time.Hour)
+	defer c.Close()
+	adapter := NewCacheAdapter(c)
+
+	repo := &racingRepo{orders: []models.Order{testOrder("changed", "OLD"), testOrder("kept", "KEPT")}}
+	// Смена статуса фиксируется в БД и удаляет заказ из кеша после того,
+	// как прогрев уже прочитал его старую версию
+	repo.onRead = func() { adapter.Delete("changed") }
+
+	s := NewOrderService(repo, adapter)
+	if err := s.LoadFromDB(context.Background()); err != nil {
+		t.Fatalf("LoadFromDB: %v", err)
+	}
+
+	if o, ok := c.Get("changed"); ok {
+		t.Errorf("в кеш записана устаревшая версия заказа: %q", o.TrackNumber)
+	}
+	if _, ok := c.Get("kept"); !ok {
+		t.Errorf("заказ kept не загружен")
+	}
+}
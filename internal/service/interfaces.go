@@ -2,14 +2,29 @@ package service
 
 import (
 	"context"
+	"errors"
+	"io"
+	"time"
 
+	"github.com/highdolen/L0/internal/cache"
 	"github.com/highdolen/L0/internal/models"
 )
 
+// ErrOrderNotFound — заказа с таким UID нет в базе данных
+var ErrOrderNotFound = errors.New("заказ не найден")
+
 // OrderResult содержит результат получения заказа с метаданными
 type OrderResult struct {
 	Order     *models.Order
 	FromCache bool
+	// Stale — заказ взят из устаревшей записи кеша, обновление запущено в фоне
+	Stale bool
+}
+
+// OrdersResult содержит заказы, найденные по вторичному ключу, с метаданными
+type OrdersResult struct {
+	Orders    []models.Order
+	FromCache bool
 }
 
 // OrderService определяет интерфейс для бизнес-логики работы с заказами
@@ -17,23 +32,69 @@ type OrderService interface {
 	// Загружает все заказы из БД в кэш при инициализации
 	LoadFromDB(ctx context.Context) error
 
+	// LoadFromSnapshot загружает кэш из файла снимка и догружает из БД заказы,
+	// созданные после снимка. Если снимка нет или он поврежден, выполняется LoadFromDB.
+	LoadFromSnapshot(ctx context.Context, path string) error
+
+	// SaveSnapshot сохраняет текущее содержимое кэша в файл снимка
+	SaveSnapshot(path string) error
+
+	// LoadRecent загружает в кэш заказы, созданные не раньше since
+	LoadRecent(ctx context.Context, since time.Time) error
+
+	// WarmUp прогревает кэш в выбранном режиме; предназначен для запуска в фоне
+	WarmUp(ctx context.Context, opts WarmupOptions) error
+
+	// WarmupStatus возвращает состояние прогрева кэша
+	WarmupStatus() WarmupStatus
+
 	// Refresh обновляет конкретный заказ в кэше из базы данных
 	Refresh(ctx context.Context, uid string) error
 
 	// GetOrderByUID получает заказ по UID с использованием кеша
 	GetOrderByUID(ctx context.Context, uid string) (*OrderResult, error)
 
+	// GetOrdersByTrackNumber получает заказы по номеру отслеживания с использованием кеша
+	GetOrdersByTrackNumber(ctx context.Context, trackNumber string) (*OrdersResult, error)
+
+	// GetOrdersByCustomer получает заказы покупателя с использованием кеша
+	GetOrdersByCustomer(ctx context.Context, customerID string) (*OrdersResult, error)
+
+	// SearchOrders ищет заказы по данным доставки и товарам; результаты упорядочены по релевантности
+	SearchOrders(ctx context.Context, query string, limit int) ([]models.OrderSearchResult, error)
+
+	// ListOrders возвращает страницу заказов из базы данных по фильтру, от новых к старым
+	ListOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderPage, error)
+
+	// ChangeOrderStatus переводит заказ в новый статус, если переход допустим,
+	// и записывает изменение в историю статусов
+	ChangeOrderStatus(ctx context.Context, change models.StatusChange) error
+
+	// GetOrderStatusHistory возвращает историю статусов заказа в порядке изменений
+	GetOrderStatusHistory(ctx context.Context, uid string) ([]models.StatusHistoryEntry, error)
+
 	// GetOrderByUIDWithRefresh принудительно обновляет заказ из БД и возвращает его
 	GetOrderByUIDWithRefresh(ctx context.Context, uid string) (*OrderResult, error)
 
 	// GetCacheStats возвращает статистику кеша
-	GetCacheStats() map[string]interface{}
+	GetCacheStats() cache.CacheStats
 
 	// InvalidateCache инвалидирует конкретный заказ в кеше
 	InvalidateCache(uid string) error
 
 	// InvalidateAllCache полностью очищает кеш
 	InvalidateAllCache() error
+
+	// ListCacheKeys возвращает страницу UID заказов в кеше с префиксом prefix после after
+	// и курсор следующей страницы (пустой — страниц больше нет)
+	ListCacheKeys(prefix, after string, limit int) ([]string, string)
+
+	// GetCacheEntry возвращает сведения о записи заказа в кеше
+	GetCacheEntry(uid string) (cache.EntryInfo, bool)
+
+	// InvalidateCacheBulk инвалидирует заказы из списка uids и закешированные заказы
+	// покупателя customerID (пустой — без фильтра) и возвращает UID инвалидированных заказов
+	InvalidateCacheBulk(uids []string, customerID string) []string
 }
 
 // OrderRepository определяет интерфейс для работы с базой данных
@@ -44,8 +105,44 @@ type OrderRepository interface {
 	// CreateOrder создает новый заказ в базе данных
 	CreateOrder(ctx context.Context, order *models.Order) error
 
+	// CreateOrders создает пакет заказов одной транзакцией
+	CreateOrders(ctx context.Context, orders []models.Order) error
+
 	// GetAllOrders получает все заказы из базы данных
 	GetAllOrders(ctx context.Context) ([]models.Order, error)
+
+	// SearchOrders выполняет полнотекстовый поиск заказов и возвращает не более limit
+	// результатов по убыванию релевантности
+	SearchOrders(ctx context.Context, query string, limit int) ([]models.OrderSearchResult, error)
+
+	// GetOrdersByTrackNumber получает все заказы с номером отслеживания, от новых к старым
+	GetOrdersByTrackNumber(ctx context.Context, trackNumber string) ([]models.Order, error)
+
+	// GetOrdersByCustomer получает все заказы покупателя, от новых к старым
+	GetOrdersByCustomer(ctx context.Context, customerID string) ([]models.Order, error)
+
+	// ListOrders возвращает страницу заказов по фильтру с пагинацией по ключу
+	ListOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderPage, error)
+
+	// GetOrderStatus получает текущий статус заказа; false — заказа нет
+	GetOrderStatus(ctx context.Context, uid string) (models.OrderStatus, bool, error)
+
+	// UpdateOrderStatus меняет статус заказа с from на change.Status и добавляет запись
+	// в историю; если статус уже не from, возвращает database.ErrStatusChanged
+	UpdateOrderStatus(ctx context.Context, from models.OrderStatus, change models.StatusChange) error
+
+	// GetStatusHistory получает историю статусов заказа (пустую, если заказа нет)
+	GetStatusHistory(ctx context.Context, uid string) ([]models.StatusHistoryEntry, error)
+
+	// StreamOrders последовательно передает все заказы из базы данных в fn
+	StreamOrders(ctx context.Context, fn func(models.Order) error) error
+
+	// StreamOrdersSince последовательно передает в fn заказы, созданные не раньше since
+	StreamOrdersSince(ctx context.Context, since time.Time, fn func(models.Order) error) error
+
+	// StreamOrdersUpdatedSince последовательно передает в fn заказы, записанные
+	// или изменённые не раньше since по часам БД
+	StreamOrdersUpdatedSince(ctx context.Context, since time.Time, fn func(models.Order) error) error
 }
 
 // CacheService определяет интерфейс для работы с кешем
@@ -53,17 +150,57 @@ type CacheService interface {
 	// Get получает заказ из кеша
 	Get(uid string) (models.Order, bool)
 
+	// Lookup получает заказ из кеша вместе с состоянием записи (актуальна или устарела)
+	Lookup(uid string) (models.Order, cache.LookupStatus)
+
 	// Set сохраняет заказ в кеш
 	Set(uid string, order models.Order)
 
+	// SetWithTTL сохраняет заказ в кеш с собственным временем жизни
+	SetWithTTL(uid string, order models.Order, ttl time.Duration)
+
+	// SetNotFound запоминает, что заказа нет в базе данных
+	SetNotFound(uid string)
+
 	// Delete удаляет заказ из кеша
 	Delete(uid string)
 
 	// InvalidateAll очищает весь кеш
 	InvalidateAll()
 
+	// LookupIndex получает из кеша все заказы с заданным track_number или customer_id;
+	// false — в кеше нет полного набора, нужно загрузить его из базы данных
+	LookupIndex(idx cache.Index, value string) ([]models.Order, bool)
+
+	// SetIndexed сохраняет в кеш полный набор заказов, загруженный по track_number или customer_id
+	SetIndexed(idx cache.Index, value string, orders []models.Order)
+
+	// DeleteIndexed удаляет из кеша все заказы с заданным track_number или customer_id
+	// и возвращает их UID
+	DeleteIndexed(idx cache.Index, value string) []string
+
+	// StartFill начинает фоновое заполнение кеша: через CacheFill не записываются
+	// заказы, инвалидированные после начала заполнения. Заполнение нужно завершить Done.
+	StartFill() CacheFill
+
+	// Keys возвращает страницу UID заказов в кеше с префиксом prefix после after
+	Keys(prefix, after string, limit int) ([]string, string)
+
+	// Entry возвращает сведения о записи заказа в кеше
+	Entry(uid string) (cache.EntryInfo, bool)
+
 	// GetStats возвращает статистику кеша
-	GetStats() map[string]interface{}
+	GetStats() cache.CacheStats
+
+	// Subscribe подписывает на события кеша (set, delete, expire, evict, flush);
+	// доставка не блокирует операции с кешем, подписку нужно закрыть
+	Subscribe(buffer int) *cache.Subscription
+
+	// SaveSnapshot записывает содержимое кеша в w
+	SaveSnapshot(w io.Writer) error
+
+	// LoadSnapshot загружает кеш из снимка и возвращает момент его создания
+	LoadSnapshot(r io.Reader) (time.Time, error)
 
 	// Close корректно завершает работу кеша
 	Close()
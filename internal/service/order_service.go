@@ -2,14 +2,30 @@ package service
 
 import (
 	"context"
-	"errors"
 	"log"
+	"time"
+
+	"github.com/highdolen/L0/internal/cache"
+	"github.com/highdolen/L0/internal/models"
 )
 
+// loadTimeout ограничивает загрузку заказа из БД при промахе кеша
+const loadTimeout = 10 * time.Second
+
 // orderService реализует интерфейс OrderService
 type orderService struct {
 	repo  OrderRepository
 	cache CacheService
+	// loads объединяет одновременные загрузки одного заказа из БД при промахе кеша
+	loads flightGroup[*models.Order]
+	// refreshes объединяет фоновые обновления устаревших записей
+	refreshes flightGroup[struct{}]
+	// lookups объединяет одновременные загрузки заказов по track_number и customer_id
+	lookups flightGroup[[]models.Order]
+	// warmup — состояние фонового прогрева кэша
+	warmup warmupTracker
+	// loadStats — число и длительность загрузок заказов из БД при промахе
+	loadStats cache.LoadRecorder
 }
 
 // NewOrderService создает новый экземпляр сервиса заказов
@@ -20,20 +36,20 @@ func NewOrderService(repo OrderRepository, cache CacheService) OrderService {
 	}
 }
 
-// Загрузка из бд в кэш
+// Загрузка из бд в кэш.
+// Заказы читаются потоком и кладутся в кэш по мере загрузки.
 func (s *orderService) LoadFromDB(ctx context.Context) error {
-	// Получаем все заказы через репозиторий
-	orders, err := s.repo.GetAllOrders(ctx)
+	fill := s.cache.StartFill()
+	defer fill.Done()
+
+	loaded, err := s.cacheOrders(fill, func(fn func(models.Order) error) error {
+		return s.repo.StreamOrders(ctx, fn)
+	})
 	if err != nil {
 		return err
 	}
 
-	// Кладём их в кэш
-	for _, order := range orders {
-		s.cache.Set(order.OrderUID, order)
-	}
-
-	log.Printf("Загружено %d заказов в кэш", len(orders))
+	log.Printf("Загружено %d заказов в кэш", loaded)
 	return nil
 }
 
@@ -59,34 +75,95 @@ func (s *orderService) Refresh(ctx context.Context, uid string) error {
 // GetOrderByUID получает заказ по UID с использованием кеша
 func (s *orderService) GetOrderByUID(ctx context.Context, uid string) (*OrderResult, error) {
 	// Сначала проверяем кеш
-	if cachedOrder, found := s.cache.Get(uid); found {
+	cachedOrder, status := s.cache.Lookup(uid)
+	switch status {
+	case cache.Hit:
 		log.Printf("Заказ %s получен из кеша", uid)
 		return &OrderResult{
 			Order:     &cachedOrder,
 			FromCache: true,
 		}, nil
+	case cache.Stale:
+		// Отдаём устаревшую запись сразу, а обновление из БД запускаем в фоне
+		log.Printf("Заказ %s получен из устаревшей записи кеша, запускаем обновление", uid)
+		s.refreshAsync(uid)
+		return &OrderResult{
+			Order:     &cachedOrder,
+			FromCache: true,
+			Stale:     true,
+		}, nil
+	case cache.NegativeHit:
+		// Недавно уже выяснили, что такого заказа нет, — не идём в БД повторно
+		log.Printf("Заказ %s отсутствует (негативная запись кеша)", uid)
+		return nil, ErrOrderNotFound
 	}
 
-	// Если в кеше нет, идем в базу данных
-	order, err := s.repo.GetOrderByUID(ctx, uid)
+	// Если в кеше нет, идем в базу данных. Одновременные запросы одного заказа
+	// объединяются: в БД уходит один запрос, остальные ждут его результата.
+	order, err, shared := s.loads.Do(ctx, uid, func() (*models.Order, error) {
+		return s.loadOrder(ctx, uid)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	if order == nil {
-		return nil, errors.New("заказ не найден")
+		return nil, ErrOrderNotFound
 	}
 
-	// Кешируем заказ для будущих запросов
-	s.cache.Set(uid, *order)
-	log.Printf("Заказ %s загружен из БД и кеширован", uid)
+	if shared {
+		log.Printf("Заказ %s получен из совместной загрузки из БД", uid)
+	}
 
+	// Каждый вызывающий получает свою копию заказа
+	result := *order
 	return &OrderResult{
-		Order:     order,
+		Order:     &result,
 		FromCache: false,
 	}, nil
 }
 
+// loadOrder загружает заказ из БД и кладёт его в кеш.
+// Загрузка не зависит от отмены запроса, который её начал: её результата
+// могут ждать другие запросы того же заказа.
+func (s *orderService) loadOrder(ctx context.Context, uid string) (*models.Order, error) {
+	loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+	defer cancel()
+
+	start := time.Now()
+	order, err := s.repo.GetOrderByUID(loadCtx, uid)
+	s.loadStats.Record(time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		// Запоминаем отсутствие заказа, чтобы не ходить в БД за ним снова
+		s.cache.SetNotFound(uid)
+		return nil, nil
+	}
+
+	// Кешируем заказ для будущих запросов
+	s.cache.Set(uid, *order)
+	log.Printf("Заказ %s загружен из БД и кеширован", uid)
+	return order, nil
+}
+
+// refreshAsync обновляет заказ в кеше в фоне.
+// Одновременные обновления одного заказа объединяются.
+func (s *orderService) refreshAsync(uid string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+		defer cancel()
+
+		_, err, shared := s.refreshes.Do(ctx, uid, func() (struct{}, error) {
+			return struct{}{}, s.Refresh(ctx, uid)
+		})
+		if err != nil && !shared {
+			log.Printf("Ошибка фонового обновления заказа %s: %v", uid, err)
+		}
+	}()
+}
+
 // GetOrderByUIDWithRefresh принудительно обновляет заказ из БД и возвращает его
 func (s *orderService) GetOrderByUIDWithRefresh(ctx context.Context, uid string) (*OrderResult, error) {
 	// Принудительно обновляем кеш из БД
@@ -102,7 +179,7 @@ func (s *orderService) GetOrderByUIDWithRefresh(ctx context.Context, uid string)
 	}
 
 	if order == nil {
-		return nil, errors.New("заказ не найден")
+		return nil, ErrOrderNotFound
 	}
 
 	return &OrderResult{
@@ -111,9 +188,23 @@ func (s *orderService) GetOrderByUIDWithRefresh(ctx context.Context, uid string)
 	}, nil
 }
 
-// GetCacheStats возвращает статистику кеша
-func (s *orderService) GetCacheStats() map[string]interface{} {
-	return s.cache.GetStats()
+// ListOrders возвращает страницу заказов из базы данных. Кеш не используется
+// и не заполняется: списки просматривают редко, а в кеше нужны часто читаемые заказы.
+func (s *orderService) ListOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderPage, error) {
+	return s.repo.ListOrders(ctx, filter)
+}
+
+// SearchOrders выполняет полнотекстовый поиск в базе данных. Как и ListOrders,
+// результаты не кешируются.
+func (s *orderService) SearchOrders(ctx context.Context, query string, limit int) ([]models.OrderSearchResult, error) {
+	return s.repo.SearchOrders(ctx, query, limit)
+}
+
+// GetCacheStats возвращает статистику кеша вместе со статистикой загрузок из БД
+func (s *orderService) GetCacheStats() cache.CacheStats {
+	stats := s.cache.GetStats()
+	stats.Loads = s.loadStats.Stats()
+	return stats
 }
 
 // InvalidateCache инвалидирует конкретный заказ в кеше
@@ -129,3 +220,41 @@ func (s *orderService) InvalidateAllCache() error {
 	log.Println("Весь кеш инвалидирован")
 	return nil
 }
+
+// ListCacheKeys возвращает страницу UID заказов в кеше
+func (s *orderService) ListCacheKeys(prefix, after string, limit int) ([]string, string) {
+	return s.cache.Keys(prefix, after, limit)
+}
+
+// GetCacheEntry возвращает сведения о записи заказа в кеше
+func (s *orderService) GetCacheEntry(uid string) (cache.EntryInfo, bool) {
+	return s.cache.Entry(uid)
+}
+
+// InvalidateCacheBulk инвалидирует заказы из списка и, если задан customerID,
+// все закешированные заказы этого покупателя. Заказы покупателя находятся
+// по вторичному индексу customer_id без обхода всего кеша.
+func (s *orderService) InvalidateCacheBulk(uids []string, customerID string) []string {
+	seen := make(map[string]bool, len(uids))
+	invalidated := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		if uid == "" || seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		s.cache.Delete(uid)
+		invalidated = append(invalidated, uid)
+	}
+
+	if customerID != "" {
+		removed := s.cache.DeleteIndexed(cache.ByCustomer, customerID)
+		for _, uid := range removed {
+			if !seen[uid] {
+				invalidated = append(invalidated, uid)
+			}
+		}
+	}
+
+	log.Printf("Инвалидирован кеш для %d заказов (по списку: %d, покупатель: %q)", len(invalidated), len(seen), customerID)
+	return invalidated
+}
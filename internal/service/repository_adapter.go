@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/highdolen/L0/internal/database"
 	"github.com/highdolen/L0/internal/models"
@@ -29,7 +30,62 @@ func (a *repositoryAdapter) CreateOrder(ctx context.Context, order *models.Order
 	return a.repo.CreateOrder(ctx, order)
 }
 
+// CreateOrders создает пакет заказов одной транзакцией
+func (a *repositoryAdapter) CreateOrders(ctx context.Context, orders []models.Order) error {
+	return a.repo.CreateOrders(ctx, orders)
+}
+
 // GetAllOrders получает все заказы из базы данных
 func (a *repositoryAdapter) GetAllOrders(ctx context.Context) ([]models.Order, error) {
 	return a.repo.GetAllOrders(ctx)
 }
+
+// ListOrders возвращает страницу заказов по фильтру
+func (a *repositoryAdapter) ListOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderPage, error) {
+	return a.repo.ListOrders(ctx, filter)
+}
+
+// SearchOrders выполняет полнотекстовый поиск заказов
+func (a *repositoryAdapter) SearchOrders(ctx context.Context, query string, limit int) ([]models.OrderSearchResult, error) {
+	return a.repo.SearchOrders(ctx, query, limit)
+}
+
+// GetOrdersByTrackNumber получает заказы по номеру отслеживания
+func (a *repositoryAdapter) GetOrdersByTrackNumber(ctx context.Context, trackNumber string) ([]models.Order, error) {
+	return a.repo.GetOrdersByTrackNumber(ctx, trackNumber)
+}
+
+// GetOrdersByCustomer получает заказы покупателя
+func (a *repositoryAdapter) GetOrdersByCustomer(ctx context.Context, customerID string) ([]models.Order, error) {
+	return a.repo.GetOrdersByCustomer(ctx, customerID)
+}
+
+// GetOrderStatus получает текущий статус заказа
+func (a *repositoryAdapter) GetOrderStatus(ctx context.Context, uid string) (models.OrderStatus, bool, error) {
+	return a.repo.GetOrderStatus(ctx, uid)
+}
+
+// UpdateOrderStatus меняет статус заказа и добавляет запись в историю
+func (a *repositoryAdapter) UpdateOrderStatus(ctx context.Context, from models.OrderStatus, change models.StatusChange) error {
+	return a.repo.UpdateOrderStatus(ctx, from, change)
+}
+
+// GetStatusHistory получает историю статусов заказа
+func (a *repositoryAdapter) GetStatusHistory(ctx context.Context, uid string) ([]models.StatusHistoryEntry, error) {
+	return a.repo.GetStatusHistory(ctx, uid)
+}
+
+// StreamOrders последовательно передает все заказы из базы данных в fn
+func (a *repositoryAdapter) StreamOrders(ctx context.Context, fn func(models.Order) error) error {
+	return a.repo.StreamOrders(ctx, fn)
+}
+
+// StreamOrdersSince передает в fn заказы, созданные не раньше since
+func (a *repositoryAdapter) StreamOrdersSince(ctx context.Context, since time.Time, fn func(models.Order) error) error {
+	return a.repo.StreamOrdersSince(ctx, since, fn)
+}
+
+// StreamOrdersUpdatedSince передает в fn заказы, записанные или изменённые не раньше since
+func (a *repositoryAdapter) StreamOrdersUpdatedSince(ctx context.Context, since time.Time, fn func(models.Order) error) error {
+	return a.repo.StreamOrdersUpdatedSince(ctx, since, fn)
+}
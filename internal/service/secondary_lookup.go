@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/highdolen/L0/internal/cache"
+	"github.com/highdolen/L0/internal/models"
+)
+
+// GetOrdersByTrackNumber получает заказы по номеру отслеживания. Если заказов
+// нет, возвращается ErrOrderNotFound.
+func (s *orderService) GetOrdersByTrackNumber(ctx context.Context, trackNumber string) (*OrdersResult, error) {
+	result, err := s.getOrdersByIndex(ctx, cache.ByTrackNumber, trackNumber, s.repo.GetOrdersByTrackNumber)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Orders) == 0 {
+		return nil, ErrOrderNotFound
+	}
+	return result, nil
+}
+
+// GetOrdersByCustomer получает все заказы покупателя (пустой список, если заказов нет)
+func (s *orderService) GetOrdersByCustomer(ctx context.Context, customerID string) (*OrdersResult, error) {
+	return s.getOrdersByIndex(ctx, cache.ByCustomer, customerID, s.repo.GetOrdersByCustomer)
+}
+
+// getOrdersByIndex отдаёт заказы из кеша, если там есть полный набор для value,
+// иначе загружает их из БД через load и кеширует вместе с отметкой полноты.
+// Одновременные загрузки одного набора объединяются.
+func (s *orderService) getOrdersByIndex(ctx context.Context, idx cache.Index, value string,
+	load func(ctx context.Context, value string) ([]models.Order, error)) (*OrdersResult, error) {
+	if orders, ok := s.cache.LookupIndex(idx, value); ok {
+		sortNewestFirst(orders)
+		log.Printf("Заказы по %s=%s получены из кеша (%d)", idx, value, len(orders))
+		return &OrdersResult{Orders: orders, FromCache: true}, nil
+	}
+
+	orders, err, _ := s.lookups.Do(ctx, idx.String()+":"+value, func() ([]models.Order, error) {
+		// Как и loadOrder, загрузка не зависит от отмены запроса, который её начал
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		start := time.Now()
+		orders, err := load(loadCtx, value)
+		s.loadStats.Record(time.Since(start), err)
+		if err != nil {
+			return nil, err
+		}
+		s.cache.SetIndexed(idx, value, orders)
+		log.Printf("Заказы по %s=%s загружены из БД и кешированы (%d)", idx, value, len(orders))
+		return orders, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Каждый вызывающий получает свою копию списка
+	return &OrdersResult{Orders: append([]models.Order{}, orders...)}, nil
+}
+
+// sortNewestFirst упорядочивает заказы так же, как запросы к БД: от новых к старым
+func sortNewestFirst(orders []models.Order) {
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].DateCreated.Equal(orders[j].DateCreated) {
+			return orders[i].DateCreated.After(orders[j].DateCreated)
+		}
+		return orders[i].OrderUID > orders[j].OrderUID
+	})
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errFlightPanic — результат для ожидающих, если функция вызова запаниковала
+var errFlightPanic = errors.New("объединённый вызов завершился паникой")
+
+// flightCall — выполняющийся или завершённый вызов для одного ключа
+type flightCall[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// flightGroup объединяет одновременные вызовы с одинаковым ключом:
+// функция выполняется один раз, остальные вызывающие ждут и получают её результат
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+// Do выполняет fn для ключа, если такой вызов ещё не выполняется, иначе
+// дожидается результата уже запущенного. shared сообщает, что результат
+// получен от чужого вызова. Ожидание прерывается отменой ctx, но сам вызов
+// продолжается для остальных ожидающих.
+func (g *flightGroup[T]) Do(ctx context.Context, key string, fn func() (T, error)) (val T, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.val, c.err, true
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err(), true
+		}
+	}
+
+	// Если fn запаникует, ожидающие получат errFlightPanic, а паника
+	// продолжится у вызывающего
+	c := &flightCall[T]{done: make(chan struct{}), err: errFlightPanic}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// Вызов снимается и ожидающие освобождаются, даже если fn запаниковала
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.val, c.err = fn()
+	return c.val, c.err, false
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupCoalesces(t *testing.T) {
+	var g flightGroup[int]
+	var calls atomic.Int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	const waiters = 10
+	var wg sync.WaitGroup
+	var shared atomic.Int32
+	results := make(chan int, waiters+1)
+
+	do := func() {
+		defer wg.Done()
+		v, err, sh := g.Do(context.Background(), "key", func() (int, error) {
+			calls.Add(1)
+			close(started)
+			<-release
+			return 42, nil
+		})
+		if err != nil {
+			t.Errorf("Do: %v", err)
+		}
+		if sh {
+			shared.Add(1)
+		}
+		results <- v
+	}
+
+	wg.Add(1)
+	go do()
+	<-started
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go do()
+	}
+	// Даём ожидающим присоединиться к вызову до его завершения
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("fn вызвана %d раз, want 1", n)
+	}
+	if n := shared.Load(); n != waiters {
+		t.Fatalf("shared = %d, want %d", n, waiters)
+	}
+	for v := range results {
+		if v != 42 {
+			t.Fatalf("результат %d, want 42", v)
+		}
+	}
+	if len(g.calls) != 0 {
+		t.Fatal("завершённый вызов не удалён")
+	}
+}
+
+func TestFlightGroupWaiterCancelled(t *testing.T) {
+	var g flightGroup[int]
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go g.Do(context.Background(), "key", func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err, _ := g.Do(ctx, "key", func() (int, error) { return 2, nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestFlightGroupPanic(t *testing.T) {
+	var g flightGroup[int]
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	panicked := make(chan any, 1)
+	go func() {
+		defer func() { panicked <- recover() }()
+		g.Do(context.Background(), "key", func() (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	waiter := make(chan error, 1)
+	go func() {
+		_, err, _ := g.Do(context.Background(), "key", func() (int, error) { return 0, nil })
+		waiter <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if p := <-panicked; p != "boom" {
+		t.Fatalf("паника не дошла до вызывающего: %v", p)
+	}
+	select {
+	case err := <-waiter:
+		if !errors.Is(err, errFlightPanic) {
+			t.Fatalf("ожидающий получил %v, want errFlightPanic", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ожидающий завис после паники")
+	}
+
+	// Ключ освобождён: следующий вызов выполняется заново
+	v, err, shared := g.Do(context.Background(), "key", func() (int, error) { return 7, nil })
+	if v != 7 || err != nil || shared {
+		t.Fatalf("повторный вызов = %d, %v, %v", v, err, shared)
+	}
+}
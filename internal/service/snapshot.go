@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/highdolen/L0/internal/cache"
+	"github.com/highdolen/L0/internal/models"
+)
+
+// snapshotCatchUpMargin — запас при догрузке заказов после снимка.
+// Покрывает заказы, которые были записаны в БД, пока снимок сохранялся,
+// транзакции, начатые до снимка и зафиксированные после него,
+// и небольшое расхождение часов между сервисом и сервером БД.
+const snapshotCatchUpMargin = 5 * time.Minute
+
+// LoadFromSnapshot загружает кэш из файла снимка и догружает из БД заказы,
+// записанные или изменённые после снимка. Если снимка нет или он поврежден,
+// выполняется LoadFromDB.
+//
+// Догрузка опирается на updated_at — время записи по часам сервера БД, а не на
+// date_created от продюсера, поэтому в кэш попадают и заказы, пришедшие из Kafka
+// с опозданием, и смены статуса, сделанные, пока узел был остановлен.
+func (s *orderService) LoadFromSnapshot(ctx context.Context, path string) error {
+	return s.loadFromSnapshot(ctx, path, s.LoadFromDB)
+}
+
+// loadFromSnapshot загружает снимок с догрузкой, а при его отсутствии или повреждении вызывает fallback
+func (s *orderService) loadFromSnapshot(ctx context.Context, path string, fallback func(ctx context.Context) error) error {
+	fill := s.cache.StartFill()
+	defer fill.Done()
+
+	createdAt, err := cache.LoadSnapshotFile(path, s.cache)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("Снимок кэша %s не найден, загружаем заказы из БД", path)
+		} else {
+			log.Printf("Не удалось загрузить снимок кэша %s: %v, загружаем заказы из БД", path, err)
+		}
+		return fallback(ctx)
+	}
+	// Снимок восстанавливается в обход fill.Set: убираем заказы, которые
+	// инвалидировались, пока он загружался
+	fill.Purge()
+	log.Printf("Кэш загружен из снимка %s от %s", path, createdAt.Format(time.RFC3339))
+
+	since := createdAt.Add(-snapshotCatchUpMargin)
+	loaded, err := s.cacheOrders(fill, func(fn func(models.Order) error) error {
+		return s.repo.StreamOrdersUpdatedSince(ctx, since, fn)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Догружено %d заказов, изменённых после снимка", loaded)
+	return nil
+}
+
+// SaveSnapshot сохраняет текущее содержимое кэша в файл снимка
+func (s *orderService) SaveSnapshot(path string) error {
+	start := time.Now()
+	if err := cache.SaveSnapshotFile(path, s.cache); err != nil {
+		return err
+	}
+	log.Printf("Снимок кэша сохранён в %s за %v", path, time.Since(start))
+	return nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/highdolen/L0/internal/cache"
+	"github.com/highdolen/L0/internal/models"
+)
+
+// snapshotRepo — репозиторий, отдающий заказы для полной загрузки и догрузки
+// после снимка и запоминающий, какая из них была вызвана
+type snapshotRepo struct {
+	OrderRepository
+	all     []models.Order
+	updated []models.Order
+
+	fullLoads int
+	since     time.Time
+}
+
+func (r *snapshotRepo) StreamOrders(ctx context.Context, fn func(models.Order) error) error {
+	r.fullLoads++
+	return streamAll(r.all, fn)
+}
+
+func (r *snapshotRepo) StreamOrdersUpdatedSince(ctx context.Context, since time.Time, fn func(models.Order) error) error {
+	r.since = since
+	return streamAll(r.updated, fn)
+}
+
+func streamAll(orders []models.Order, fn func(models.Order) error) error {
+	for _, o := range orders {
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func testOrder(uid, track string) models.Order {
+	return models.Order{OrderUID: uid, TrackNumber: track, CustomerID: "customer", DateCreated: time.Now()}
+}
+
+// writeSnapshot сохраняет в dir снимок кэша с заказом "snap" и возвращает путь к файлу
+func writeSnapshot(t *testing.T, dir string) string {
+	t.Helper()
+	c := cache.New(time.Hour)
+	defer c.Close()
+	c.Set("snap", testOrder("snap", "SNAP"))
+
+	path := filepath.Join(dir, "cache.snapshot")
+	if err := cache.SaveSnapshotFile(path, c); err != nil {
+		t.Fatalf("SaveSnapshotFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadFromSnapshotCatchUp(t *testing.T) {
+	path := writeSnapshot(t, t.TempDir())
+
+	c := cache.New(time.Hour)
+	defer c.Close()
+	repo := &snapshotRepo{
+		all:     []models.Order{testOrder("db", "DB")},
+		updated: []models.Order{testOrder("snap", "SNAP-NEW"), testOrder("late", "LATE")},
+	}
+	s := NewOrderService(repo, NewCacheAdapter(c))
+
+	start := time.Now()
+	if err := s.LoadFromSnapshot(context.Background(), path); err != nil {
+		t.Fatalf("LoadFromSnapshot: %v", err)
+	}
+
+	if repo.fullLoads != 0 {
+		t.Errorf("при целом снимке выполнена полная загрузка из БД")
+	}
+	if repo.since.After(start.Add(-snapshotCatchUpMargin)) {
+		t.Errorf("догрузка с %v не учитывает запас %v", repo.since, snapshotCatchUpMargin)
+	}
+	// Заказ из снимка обновлён догрузкой, опоздавший заказ добавлен
+	if o, ok := c.Get("snap"); !ok || o.TrackNumber != "SNAP-NEW" {
+		t.Errorf("заказ snap = %q, %v; ожидалась версия из догрузки", o.TrackNumber, ok)
+	}
+	if _, ok := c.Get("late"); !ok {
+		t.Errorf("изменённый после снимка заказ не догружен")
+	}
+	if _, ok := c.Get("db"); ok {
+		t.Errorf("при целом снимке в кэш попал заказ из полной загрузки")
+	}
+}
+
+func TestLoadFromSnapshotFallsBackToDB(t *testing.T) {
+	dir := t.TempDir()
+	valid, err := os.ReadFile(writeSnapshot(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte // nil — файла нет
+	}{
+		{"нет файла", nil},
+		{"обрезан", valid[:len(valid)/2]},
+		{"испорчены данные", append(slices.Clone(valid[:len(valid)-8]), 0xde, 0xad, 0xbe, 0xef, 0, 0, 0, 0)},
+		{"не снимок", []byte("garbage")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.snapshot")
+			if tt.data != nil {
+				if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c := cache.New(time.Hour)
+			defer c.Close()
+			repo := &snapshotRepo{all: []models.Order{testOrder("db", "DB")}}
+			s := NewOrderService(repo, NewCacheAdapter(c))
+
+			if err := s.LoadFromSnapshot(context.Background(), path); err != nil {
+				t.Fatalf("LoadFromSnapshot: %v", err)
+			}
+			if repo.fullLoads != 1 {
+				t.Errorf("полных загрузок из БД: %d, ожидалась 1", repo.fullLoads)
+			}
+			if _, ok := c.Get("db"); !ok {
+				t.Errorf("заказ из БД не загружен в кэш")
+			}
+			if _, ok := c.Get("snap"); ok {
+				t.Errorf("в кэш попал заказ из повреждённого снимка")
+			}
+		})
+	}
+}
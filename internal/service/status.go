@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/highdolen/L0/internal/database"
+	"github.com/highdolen/L0/internal/models"
+)
+
+var (
+	// ErrInvalidStatus — в событии указан неизвестный статус заказа
+	ErrInvalidStatus = errors.New("неизвестный статус заказа")
+	// ErrInvalidTransition — переход из текущего статуса заказа в указанный недопустим
+	ErrInvalidTransition = errors.New("недопустимый переход статуса")
+	// ErrMissingChangedAt — в событии не указано время изменения статуса
+	ErrMissingChangedAt = errors.New("не указано время изменения статуса")
+)
+
+// statusUpdateAttempts — сколько раз ChangeOrderStatus перечитывает статус,
+// если его одновременно изменил другой обработчик
+const statusUpdateAttempts = 3
+
+// transitions — конечный автомат статусов заказа: допустимые переходы из каждого статуса.
+// Отменить можно только ещё не отправленный заказ, вернуть — отправленный или доставленный.
+// Из cancelled и returned переходов нет.
+var transitions = map[models.OrderStatus][]models.OrderStatus{
+	models.StatusCreated:    {models.StatusPaid, models.StatusCancelled},
+	models.StatusPaid:       {models.StatusAssembling, models.StatusCancelled},
+	models.StatusAssembling: {models.StatusShipped, models.StatusCancelled},
+	models.StatusShipped:    {models.StatusDelivered, models.StatusReturned},
+	models.StatusDelivered:  {models.StatusReturned},
+}
+
+// CanTransition сообщает, допустим ли переход статуса заказа из from в to
+func CanTransition(from, to models.OrderStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeOrderStatus переводит заказ в статус change.Status, проверяя переход по
+// конечному автомату, и записывает изменение в историю. Повторное событие с тем же
+// статусом ничего не меняет. Событие без ChangedAt отклоняется с ErrMissingChangedAt,
+// если заказа нет, возвращается ErrOrderNotFound.
+// Запись заказа в кеше после изменения инвалидируется.
+func (s *orderService) ChangeOrderStatus(ctx context.Context, change models.StatusChange) error {
+	if !change.Status.Valid() {
+		return fmt.Errorf("%w: %q", ErrInvalidStatus, change.Status)
+	}
+	// Время обработки вместо changed_at сделало бы повторную доставку события
+	// неотличимой от нового изменения
+	if change.ChangedAt.IsZero() {
+		return ErrMissingChangedAt
+	}
+
+	for attempt := 1; ; attempt++ {
+		current, found, err := s.repo.GetOrderStatus(ctx, change.OrderUID)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrOrderNotFound
+		}
+		if current == change.Status {
+			log.Printf("Заказ %s уже в статусе %s, событие пропущено", change.OrderUID, current)
+			return nil
+		}
+		if !CanTransition(current, change.Status) {
+			applied, err := s.statusApplied(ctx, change)
+			if err != nil {
+				return err
+			}
+			if applied {
+				log.Printf("Переход заказа %s в статус %s уже применён, событие пропущено", change.OrderUID, change.Status)
+				return nil
+			}
+			return fmt.Errorf("%w: %s → %s", ErrInvalidTransition, current, change.Status)
+		}
+
+		err = s.repo.UpdateOrderStatus(ctx, current, change)
+		if errors.Is(err, database.ErrStatusChanged) && attempt < statusUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		s.cache.Delete(change.OrderUID)
+		log.Printf("Статус заказа %s изменён: %s → %s", change.OrderUID, current, change.Status)
+		return nil
+	}
+}
+
+// statusApplied проверяет, есть ли в истории заказа это же изменение статуса.
+// Так распознаётся повторная доставка события, за которым уже были другие переходы.
+func (s *orderService) statusApplied(ctx context.Context, change models.StatusChange) (bool, error) {
+	history, err := s.repo.GetStatusHistory(ctx, change.OrderUID)
+	if err != nil {
+		return false, err
+	}
+	// PostgreSQL хранит время с точностью до микросекунд
+	at := change.ChangedAt.Truncate(time.Microsecond)
+	for _, e := range history {
+		if e.To == change.Status && e.ChangedAt.Equal(at) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetOrderStatusHistory возвращает историю статусов заказа в порядке изменений.
+// Если заказа нет, возвращается ErrOrderNotFound.
+func (s *orderService) GetOrderStatusHistory(ctx context.Context, uid string) ([]models.StatusHistoryEntry, error) {
+	history, err := s.repo.GetStatusHistory(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	// У каждого сохранённого заказа есть хотя бы начальная запись created
+	if len(history) == 0 {
+		return nil, ErrOrderNotFound
+	}
+	return history, nil
+}
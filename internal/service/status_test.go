@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/highdolen/L0/internal/cache"
+	"github.com/highdolen/L0/internal/database"
+	"github.com/highdolen/L0/internal/models"
+)
+
+func TestCanTransition(t *testing.T) {
+	all := []models.OrderStatus{
+		models.StatusCreated, models.StatusPaid, models.StatusAssembling, models.StatusShipped,
+		models.StatusDelivered, models.StatusCancelled, models.StatusReturned,
+	}
+	allowed := map[[2]models.OrderStatus]bool{
+		{models.StatusCreated, models.StatusPaid}:         true,
+		{models.StatusCreated, models.StatusCancelled}:    true,
+		{models.StatusPaid, models.StatusAssembling}:      true,
+		{models.StatusPaid, models.StatusCancelled}:       true,
+		{models.StatusAssembling, models.StatusShipped}:   true,
+		{models.StatusAssembling, models.StatusCancelled}: true,
+		{models.StatusShipped, models.StatusDelivered}:    true,
+		{models.StatusShipped, models.StatusReturned}:     true,
+		{models.StatusDelivered, models.StatusReturned}:   true,
+	}
+
+	// Проверяются все пары статусов: любой переход вне таблицы запрещён,
+	// в том числе в тот же статус, из конечных статусов и из неизвестного
+	for _, from := range append(all, "unknown") {
+		for _, to := range append(all, "unknown") {
+			want := allowed[[2]models.OrderStatus{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, ожидалось %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+// statusRepo — репозиторий статусов в памяти
+type statusRepo struct {
+	OrderRepository
+	status  map[string]models.OrderStatus
+	history map[string][]models.StatusHistoryEntry
+	// conflicts — сколько следующих обновлений завершится ErrStatusChanged
+	conflicts int
+	updates   int
+}
+
+func newStatusRepo(uid string, history ...models.StatusHistoryEntry) *statusRepo {
+	r := &statusRepo{
+		status:  make(map[string]models.OrderStatus),
+		history: make(map[string][]models.StatusHistoryEntry),
+	}
+	if len(history) > 0 {
+		r.status[uid] = history[len(history)-1].To
+		r.history[uid] = history
+	}
+	return r
+}
+
+func (r *statusRepo) GetOrderStatus(ctx context.Context, uid string) (models.OrderStatus, bool, error) {
+	status, ok := r.status[uid]
+	return status, ok, nil
+}
+
+func (r *statusRepo) UpdateOrderStatus(ctx context.Context, from models.OrderStatus, change models.StatusChange) error {
+	r.updates++
+	if r.conflicts > 0 {
+		r.conflicts--
+		return database.ErrStatusChanged
+	}
+	if r.status[change.OrderUID] != from {
+		return database.ErrStatusChanged
+	}
+	r.status[change.OrderUID] = change.Status
+	// Как и PostgreSQL, храним время с точностью до микросекунд
+	r.history[change.OrderUID] = append(r.history[change.OrderUID], models.StatusHistoryEntry{
+		From: from, To: change.Status, ChangedAt: change.ChangedAt.Truncate(time.Microsecond), Reason: change.Reason,
+	})
+	return nil
+}
+
+func (r *statusRepo) GetStatusHistory(ctx context.Context, uid string) ([]models.StatusHistoryEntry, error) {
+	return append([]models.StatusHistoryEntry{}, r.history[uid]...), nil
+}
+
+func TestChangeOrderStatus(t *testing.T) {
+	const uid = "order"
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	// paidAt с наносекундами: в истории оно хранится усечённым до микросекунд
+	paidAt := t0.Add(time.Hour + 123456789)
+
+	created := models.StatusHistoryEntry{To: models.StatusCreated, ChangedAt: t0}
+	paid := models.StatusHistoryEntry{From: models.StatusCreated, To: models.StatusPaid, ChangedAt: paidAt.Truncate(time.Microsecond)}
+	assembling := models.StatusHistoryEntry{From: models.StatusPaid, To: models.StatusAssembling, ChangedAt: t0.Add(2 * time.Hour)}
+
+	change := func(status models.OrderStatus, at time.Time) models.StatusChange {
+		return models.StatusChange{OrderUID: uid, Status: status, ChangedAt: at}
+	}
+
+	tests := []struct {
+		name      string
+		history   []models.StatusHistoryEntry // пусто — заказа нет
+		conflicts int
+		change    models.StatusChange
+		wantErr   error
+		// wantStatus и wantHistory — состояние после вызова
+		wantStatus  models.OrderStatus
+		wantHistory int
+		// wantInvalidated — запись заказа удалена из кеша
+		wantInvalidated bool
+	}{
+		{
+			name:       "допустимый переход",
+			history:    []models.StatusHistoryEntry{created},
+			change:     change(models.StatusPaid, paidAt),
+			wantStatus: models.StatusPaid, wantHistory: 2, wantInvalidated: true,
+		},
+		{
+			name:       "повтор с текущим статусом",
+			history:    []models.StatusHistoryEntry{created, paid},
+			change:     change(models.StatusPaid, paidAt),
+			wantStatus: models.StatusPaid, wantHistory: 2,
+		},
+		{
+			name:       "повтор уже применённого события после следующих переходов",
+			history:    []models.StatusHistoryEntry{created, paid, assembling},
+			change:     change(models.StatusPaid, paidAt),
+			wantStatus: models.StatusAssembling, wantHistory: 3,
+		},
+		{
+			name:    "тот же статус с другим временем после следующих переходов",
+			history: []models.StatusHistoryEntry{created, paid, assembling},
+			change:  change(models.StatusPaid, paidAt.Add(time.Second)),
+			wantErr: ErrInvalidTransition, wantStatus: models.StatusAssembling, wantHistory: 3,
+		},
+		{
+			name:    "недопустимый переход",
+			history: []models.StatusHistoryEntry{created},
+			change:  change(models.StatusDelivered, paidAt),
+			wantErr: ErrInvalidTransition, wantStatus: models.StatusCreated, wantHistory: 1,
+		},
+		{
+			name:    "неизвестный статус",
+			history: []models.StatusHistoryEntry{created},
+			change:  change("lost", paidAt),
+			wantErr: ErrInvalidStatus, wantStatus: models.StatusCreated, wantHistory: 1,
+		},
+		{
+			name:    "нет changed_at",
+			history: []models.StatusHistoryEntry{created},
+			change:  change(models.StatusPaid, time.Time{}),
+			wantErr: ErrMissingChangedAt, wantStatus: models.StatusCreated, wantHistory: 1,
+		},
+		{
+			name:    "заказа нет",
+			change:  change(models.StatusPaid, paidAt),
+			wantErr: ErrOrderNotFound,
+		},
+		{
+			name:       "статус изменён одновременно, повтор успешен",
+			history:    []models.StatusHistoryEntry{created},
+			conflicts:  statusUpdateAttempts - 1,
+			change:     change(models.StatusPaid, paidAt),
+			wantStatus: models.StatusPaid, wantHistory: 2, wantInvalidated: true,
+		},
+		{
+			name:      "статус изменён одновременно на каждой попытке",
+			history:   []models.StatusHistoryEntry{created},
+			conflicts: statusUpdateAttempts,
+			change:    change(models.StatusPaid, paidAt),
+			wantErr:   database.ErrStatusChanged, wantStatus: models.StatusCreated, wantHistory: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newStatusRepo(uid, tt.history...)
+			repo.conflicts = tt.conflicts
+			c := cache.New(time.Hour)
+			defer c.Close()
+			c.Set(uid, models.Order{OrderUID: uid})
+			s := NewOrderService(repo, NewCacheAdapter(c))
+
+			err := s.ChangeOrderStatus(context.Background(), tt.change)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChangeOrderStatus вернул %v, ожидалось %v", err, tt.wantErr)
+			}
+			if got := repo.status[uid]; got != tt.wantStatus {
+				t.Errorf("статус %q, ожидался %q", got, tt.wantStatus)
+			}
+			if got := len(repo.history[uid]); got != tt.wantHistory {
+				t.Errorf("записей в истории %d, ожидалось %d", got, tt.wantHistory)
+			}
+			if _, cached := c.Get(uid); cached == tt.wantInvalidated {
+				t.Errorf("заказ в кеше: %v, ожидалась инвалидация: %v", cached, tt.wantInvalidated)
+			}
+			if tt.conflicts > 0 && repo.updates > statusUpdateAttempts {
+				t.Errorf("попыток обновления %d, больше %d", repo.updates, statusUpdateAttempts)
+			}
+		})
+	}
+}
+
+// TestChangeOrderStatusIdempotent применяет последовательность событий с повторной
+// доставкой каждого из них: история должна совпасть с однократным применением
+func TestChangeOrderStatusIdempotent(t *testing.T) {
+	const uid = "order"
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	repo := newStatusRepo(uid, models.StatusHistoryEntry{To: models.StatusCreated, ChangedAt: t0})
+	c := cache.New(time.Hour)
+	defer c.Close()
+	s := NewOrderService(repo, NewCacheAdapter(c))
+
+	events := []models.StatusChange{
+		{OrderUID: uid, Status: models.StatusPaid, ChangedAt: t0.Add(time.Hour + 999), Reason: "оплата"},
+		{OrderUID: uid, Status: models.StatusAssembling, ChangedAt: t0.Add(2 * time.Hour)},
+		{OrderUID: uid, Status: models.StatusShipped, ChangedAt: t0.Add(3 * time.Hour)},
+	}
+	// Каждое событие доставляется дважды, а затем вся пачка — ещё раз
+	var deliveries []models.StatusChange
+	for _, e := range events {
+		deliveries = append(deliveries, e, e)
+	}
+	deliveries = append(deliveries, events...)
+
+	for i, e := range deliveries {
+		if err := s.ChangeOrderStatus(context.Background(), e); err != nil {
+			t.Fatalf("доставка %d (%s): %v", i, e.Status, err)
+		}
+	}
+
+	history, err := s.GetOrderStatusHistory(context.Background(), uid)
+	if err != nil {
+		t.Fatalf("GetOrderStatusHistory: %v", err)
+	}
+	want := []models.OrderStatus{models.StatusCreated, models.StatusPaid, models.StatusAssembling, models.StatusShipped}
+	if len(history) != len(want) {
+		t.Fatalf("история %+v, ожидались статусы %v", history, want)
+	}
+	for i, e := range history {
+		if e.To != want[i] {
+			t.Errorf("запись %d истории: %s, ожидался %s", i, e.To, want[i])
+		}
+		if i > 0 && e.From != want[i-1] {
+			t.Errorf("запись %d истории: переход из %s, ожидался из %s", i, e.From, want[i-1])
+		}
+	}
+	if repo.updates != len(events) {
+		t.Errorf("обновлений статуса %d, ожидалось %d", repo.updates, len(events))
+	}
+}
+
+func TestGetOrderStatusHistoryNotFound(t *testing.T) {
+	c := cache.New(time.Hour)
+	defer c.Close()
+	s := NewOrderService(newStatusRepo("order"), NewCacheAdapter(c))
+
+	if _, err := s.GetOrderStatusHistory(context.Background(), "missing"); !errors.Is(err, ErrOrderNotFound) {
+		t.Fatalf("GetOrderStatusHistory вернул %v, ожидалась ErrOrderNotFound", err)
+	}
+}
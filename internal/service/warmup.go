@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/highdolen/L0/internal/models"
+)
+
+// WarmupMode — какие заказы загружаются в кэш при старте
+type WarmupMode string
+
+const (
+	// WarmupFull — все заказы из БД
+	WarmupFull WarmupMode = "full"
+	// WarmupRecent — только заказы, созданные за последние RecentDays дней
+	WarmupRecent WarmupMode = "recent"
+	// WarmupNone — кэш заполняется только по промахам
+	WarmupNone WarmupMode = "none"
+)
+
+// ParseWarmupMode проверяет название режима прогрева
+func ParseWarmupMode(name string) (WarmupMode, error) {
+	switch mode := WarmupMode(name); mode {
+	case WarmupFull, WarmupRecent, WarmupNone:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown warmup mode %q", name)
+	}
+}
+
+// WarmupOptions — параметры прогрева кэша
+type WarmupOptions struct {
+	Mode WarmupMode
+	// RecentDays — глубина загрузки для WarmupRecent
+	RecentDays int
+	// SnapshotPath — файл снимка кэша; если задан, прогрев начинается с него
+	SnapshotPath string
+}
+
+// WarmupState — стадия прогрева кэша
+type WarmupState string
+
+const (
+	WarmupPending WarmupState = "pending"
+	WarmupRunning WarmupState = "running"
+	WarmupReady   WarmupState = "ready"
+	WarmupFailed  WarmupState = "failed"
+)
+
+// WarmupStatus — состояние прогрева для readiness-проверки
+type WarmupStatus struct {
+	Mode       WarmupMode  `json:"mode"`
+	State      WarmupState `json:"state"`
+	Loaded     int64       `json:"loaded"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Ready сообщает, что прогрев завершён. Неудачный прогрев тоже считается
+// завершённым: сервис продолжает работать, загружая заказы по промахам.
+func (st WarmupStatus) Ready() bool {
+	return st.State == WarmupReady || st.State == WarmupFailed
+}
+
+// warmupTracker хранит состояние прогрева; loaded обновляется по мере загрузки
+type warmupTracker struct {
+	mu     sync.Mutex
+	status WarmupStatus
+	loaded atomic.Int64
+}
+
+func (t *warmupTracker) set(update func(st *WarmupStatus)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	update(&t.status)
+}
+
+func (t *warmupTracker) get() WarmupStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.status
+	if st.State == "" {
+		st.State = WarmupPending
+	}
+	st.Loaded = t.loaded.Load()
+	return st
+}
+
+// WarmUp прогревает кэш в выбранном режиме. Предназначен для запуска в фоне:
+// пока он работает, заказы отдаются через загрузку из БД по промаху.
+func (s *orderService) WarmUp(ctx context.Context, opts WarmupOptions) error {
+	start := time.Now()
+	s.warmup.set(func(st *WarmupStatus) {
+		st.Mode = opts.Mode
+		st.State = WarmupRunning
+		st.StartedAt = &start
+	})
+
+	err := s.warmUp(ctx, opts)
+
+	s.warmup.set(func(st *WarmupStatus) {
+		finished := time.Now()
+		st.FinishedAt = &finished
+		if err != nil {
+			st.State = WarmupFailed
+			st.Error = err.Error()
+		} else {
+			st.State = WarmupReady
+		}
+	})
+	if err != nil {
+		log.Printf("Ошибка прогрева кэша (режим %s): %v", opts.Mode, err)
+		return err
+	}
+	log.Printf("Прогрев кэша (режим %s) завершён за %v, загружено %d заказов",
+		opts.Mode, time.Since(start), s.warmup.loaded.Load())
+	return nil
+}
+
+func (s *orderService) warmUp(ctx context.Context, opts WarmupOptions) error {
+	var load func(ctx context.Context) error
+	switch opts.Mode {
+	case WarmupNone:
+		log.Println("Прогрев кэша отключен, заказы загружаются по запросу")
+		return nil
+	case WarmupRecent:
+		load = func(ctx context.Context) error {
+			return s.LoadRecent(ctx, time.Now().AddDate(0, 0, -opts.RecentDays))
+		}
+	default:
+		load = s.LoadFromDB
+	}
+
+	if opts.SnapshotPath != "" {
+		return s.loadFromSnapshot(ctx, opts.SnapshotPath, load)
+	}
+	return load(ctx)
+}
+
+// LoadRecent загружает в кэш заказы, созданные не раньше since
+func (s *orderService) LoadRecent(ctx context.Context, since time.Time) error {
+	fill := s.cache.StartFill()
+	defer fill.Done()
+
+	loaded, err := s.cacheOrders(fill, func(fn func(models.Order) error) error {
+		return s.repo.StreamOrdersSince(ctx, since, fn)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Загружено %d заказов, созданных после %s", loaded, since.Format(time.RFC3339))
+	return nil
+}
+
+// WarmupStatus возвращает текущее состояние прогрева кэша
+func (s *orderService) WarmupStatus() WarmupStatus {
+	return s.warmup.get()
+}
+
+// cacheOrders кладёт в кэш через fill заказы из потока stream и возвращает их число.
+// Заказы, инвалидированные с начала заполнения, пропускаются. Прогресс учитывается
+// в состоянии прогрева и периодически логируется.
+func (s *orderService) cacheOrders(fill CacheFill, stream func(fn func(models.Order) error) error) (int, error) {
+	loaded := 0
+	err := stream(func(order models.Order) error {
+		if !fill.Set(order.OrderUID, order) {
+			log.Printf("Заказ %s инвалидирован во время загрузки, в кэш не записан", order.OrderUID)
+			return nil
+		}
+		loaded++
+		if total := s.warmup.loaded.Add(1); total%10000 == 0 {
+			log.Printf("Загружено %d заказов в кэш...", total)
+		}
+		return nil
+	})
+	return loaded, err
+}
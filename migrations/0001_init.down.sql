@@ -0,0 +1,6 @@
+-- 0001_init: удаление исходной схемы заказов вместе с данными
+
+DROP TABLE IF EXISTS items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS payment;
+DROP TABLE IF EXISTS delivery;
@@ -1,6 +1,7 @@
--- migrations/01_create_tables.sql
+-- 0001_init: исходная схема заказов.
+-- IF NOT EXISTS позволяет принять под управление базу, созданную вручную до появления миграций.
 
-CREATE TABLE delivery (
+CREATE TABLE IF NOT EXISTS delivery (
     id BIGSERIAL PRIMARY KEY,
     name TEXT,
     phone TEXT,
@@ -11,7 +12,7 @@ CREATE TABLE delivery (
     email TEXT
 );
 
-CREATE TABLE payment (
+CREATE TABLE IF NOT EXISTS payment (
     id BIGSERIAL PRIMARY KEY,
     transaction TEXT,
     request_id TEXT,
@@ -25,12 +26,12 @@ CREATE TABLE payment (
     custom_fee BIGINT
 );
 
-CREATE TABLE orders (
+CREATE TABLE IF NOT EXISTS orders (
     order_uid TEXT PRIMARY KEY,
     track_number TEXT,
     entry TEXT,
-    delivery_id BIGINT REFERENCES deliveries(id) ON DELETE SET NULL,
-    payment_id BIGINT REFERENCES payments(id) ON DELETE SET NULL,
+    delivery_id BIGINT REFERENCES delivery(id) ON DELETE SET NULL,
+    payment_id BIGINT REFERENCES payment(id) ON DELETE SET NULL,
     locale TEXT,
     internal_signature TEXT,
     customer_id TEXT,
@@ -41,7 +42,7 @@ CREATE TABLE orders (
     oof_shard TEXT
 );
 
-CREATE TABLE items (
+CREATE TABLE IF NOT EXISTS items (
     id BIGSERIAL PRIMARY KEY,
     chrt_id BIGINT,
     track_number TEXT,
@@ -58,6 +59,6 @@ CREATE TABLE items (
 );
 
 -- Индексы
-CREATE INDEX idx_orders_customer_id ON orders(customer_id);
-CREATE INDEX idx_items_order_uid ON items(order_uid);
-CREATE INDEX idx_orders_track_number ON orders(track_number);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id);
+CREATE INDEX IF NOT EXISTS idx_items_order_uid ON items(order_uid);
+CREATE INDEX IF NOT EXISTS idx_orders_track_number ON orders(track_number);
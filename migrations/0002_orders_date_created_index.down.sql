@@ -0,0 +1,3 @@
+-- 0002_orders_date_created_index: удаление индекса списка заказов
+
+DROP INDEX IF EXISTS idx_orders_date_created;
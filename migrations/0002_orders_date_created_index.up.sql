@@ -0,0 +1,4 @@
+-- 0002_orders_date_created_index: индекс для списка заказов от новых к старым
+-- с пагинацией по ключу (date_created, order_uid)
+
+CREATE INDEX IF NOT EXISTS idx_orders_date_created ON orders(date_created DESC, order_uid DESC);
@@ -0,0 +1,6 @@
+-- 0003_search_vectors: удаление столбцов и индексов полнотекстового поиска
+
+DROP INDEX IF EXISTS idx_items_search;
+DROP INDEX IF EXISTS idx_delivery_search;
+ALTER TABLE items DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE delivery DROP COLUMN IF EXISTS search_vector;
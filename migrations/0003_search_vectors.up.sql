@@ -0,0 +1,20 @@
+-- 0003_search_vectors: полнотекстовый поиск по данным доставки и товарам.
+-- Столбцы вычисляются самим PostgreSQL при вставке и обновлении строки.
+-- Конфигурация simple не отбрасывает слова и не приводит их к основе:
+-- имена, города, адреса и бренды ищутся как есть, без привязки к языку.
+
+ALTER TABLE delivery ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(city, '') || ' ' || coalesce(address, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(email, '')), 'C')
+    ) STORED;
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(brand, '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_delivery_search ON delivery USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_items_search ON items USING GIN (search_vector);
@@ -0,0 +1,4 @@
+-- 0004_order_status: удаление истории и статуса заказа
+
+DROP TABLE IF EXISTS order_status_history;
+ALTER TABLE orders DROP COLUMN IF EXISTS status;
@@ -0,0 +1,22 @@
+-- 0004_order_status: статус заказа и история его изменений.
+-- Допустимые переходы проверяются сервисом; CHECK защищает от неизвестных значений.
+-- Существующие заказы получают статус created и начальную запись в истории.
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'created'
+    CHECK (status IN ('created', 'paid', 'assembling', 'shipped', 'delivered', 'cancelled', 'returned'));
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid TEXT NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL,
+    reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history(order_uid, id);
+
+INSERT INTO order_status_history (order_uid, from_status, to_status, changed_at)
+SELECT o.order_uid, NULL, 'created', o.date_created
+FROM orders o
+WHERE NOT EXISTS (SELECT 1 FROM order_status_history h WHERE h.order_uid = o.order_uid);
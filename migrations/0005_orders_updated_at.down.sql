@@ -0,0 +1,4 @@
+-- 0005_orders_updated_at: удаление времени изменения заказа
+
+DROP INDEX IF EXISTS idx_orders_updated_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,8 @@
+-- 0005_orders_updated_at: время последнего изменения заказа по часам сервера БД.
+-- Используется для догрузки кэша после снимка: в отличие от date_created, который
+-- задаёт продюсер, updated_at отражает момент записи и смены статуса.
+-- Существующие заказы получают время применения миграции.
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_orders_updated_at ON orders(updated_at);
@@ -0,0 +1,101 @@
+// Package migrations содержит версионированные SQL-миграции схемы БД,
+// встроенные в бинарник сервиса, и их исполнитель.
+//
+// Файлы миграций называются NNNN_описание.up.sql и NNNN_описание.down.sql,
+// где NNNN — номер версии. Каждая версия обязана иметь обе части.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Migration — одна версия схемы
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// All возвращает встроенные миграции по возрастанию версии
+func All() ([]Migration, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range names {
+		version, name, direction, err := parseFileName(file)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("миграция %d: разные имена %q и %q", version, m.Name, name)
+		}
+		part := &m.Down
+		if direction == "up" {
+			part = &m.Up
+		}
+		// Одна версия может быть записана по-разному (0001_init и 1_init)
+		if *part != "" {
+			return nil, fmt.Errorf("миграция %d: повторяется файл .%s.sql (%s)", version, direction, file)
+		}
+		*part = string(data)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("миграция %04d_%s: нужны файлы .up.sql и .down.sql", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// parseFileName разбирает имя вида 0001_init.up.sql
+func parseFileName(file string) (version int64, name, direction string, err error) {
+	base := strings.TrimSuffix(file, ".sql")
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("файл миграции %s: ожидается суффикс .up.sql или .down.sql", file)
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+
+	num, name, ok := strings.Cut(base, "_")
+	if !ok || name == "" {
+		return 0, "", "", fmt.Errorf("файл миграции %s: ожидается имя NNNN_описание", file)
+	}
+	version, err = strconv.ParseInt(num, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("файл миграции %s: некорректный номер версии", file)
+	}
+	return version, name, direction, nil
+}
@@ -0,0 +1,156 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseFileName(t *testing.T) {
+	tests := []struct {
+		file          string
+		wantVersion   int64
+		wantName      string
+		wantDirection string
+		wantErr       bool
+	}{
+		{file: "0001_init.up.sql", wantVersion: 1, wantName: "init", wantDirection: "up"},
+		{file: "0012_add_index.down.sql", wantVersion: 12, wantName: "add_index", wantDirection: "down"},
+		{file: "3_short.up.sql", wantVersion: 3, wantName: "short", wantDirection: "up"},
+		{file: "0001_init.sql", wantErr: true},
+		{file: "0001_init.sideways.sql", wantErr: true},
+		{file: "0001.up.sql", wantErr: true},
+		{file: "0001_.up.sql", wantErr: true},
+		{file: "init_0001.up.sql", wantErr: true},
+		{file: "0000_zero.up.sql", wantErr: true},
+		{file: "-001_negative.up.sql", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			version, name, direction, err := parseFileName(tt.file)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("имя %s принято: %d %q %q", tt.file, version, name, direction)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFileName: %v", err)
+			}
+			if version != tt.wantVersion || name != tt.wantName || direction != tt.wantDirection {
+				t.Errorf("получено %d %q %q, ожидалось %d %q %q",
+					version, name, direction, tt.wantVersion, tt.wantName, tt.wantDirection)
+			}
+		})
+	}
+}
+
+func sqlFile(s string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(s)}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_later.up.sql":    sqlFile("UP 10"),
+		"0010_later.down.sql":  sqlFile("DOWN 10"),
+		"0002_second.up.sql":   sqlFile("UP 2"),
+		"0002_second.down.sql": sqlFile("DOWN 2"),
+		"0001_init.up.sql":     sqlFile("UP 1"),
+		"0001_init.down.sql":   sqlFile("DOWN 1"),
+		"README.md":            sqlFile("не миграция"),
+	}
+
+	got, err := load(fsys)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	want := []Migration{
+		{Version: 1, Name: "init", Up: "UP 1", Down: "DOWN 1"},
+		{Version: 2, Name: "second", Up: "UP 2", Down: "DOWN 2"},
+		{Version: 10, Name: "later", Up: "UP 10", Down: "DOWN 10"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("загружено %d миграций, ожидалось %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("миграция %d = %+v, ожидалась %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "нет down",
+			fsys: fstest.MapFS{
+				"0001_init.up.sql": sqlFile("UP"),
+			},
+			wantErr: "нужны файлы",
+		},
+		{
+			name: "нет up",
+			fsys: fstest.MapFS{
+				"0001_init.down.sql": sqlFile("DOWN"),
+			},
+			wantErr: "нужны файлы",
+		},
+		{
+			name: "одна версия с разными именами",
+			fsys: fstest.MapFS{
+				"0001_init.up.sql":    sqlFile("UP"),
+				"0001_init.down.sql":  sqlFile("DOWN"),
+				"0001_other.up.sql":   sqlFile("UP"),
+				"0001_other.down.sql": sqlFile("DOWN"),
+			},
+			wantErr: "разные имена",
+		},
+		{
+			name: "одна версия с разной записью номера",
+			fsys: fstest.MapFS{
+				"0001_init.up.sql":   sqlFile("UP"),
+				"0001_init.down.sql": sqlFile("DOWN"),
+				"1_init.up.sql":      sqlFile("UP again"),
+			},
+			wantErr: "повторяется",
+		},
+		{
+			name: "некорректное имя",
+			fsys: fstest.MapFS{
+				"init.up.sql": sqlFile("UP"),
+			},
+			wantErr: "NNNN_описание",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("load вернул %v, ожидалась ошибка с %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestAll проверяет встроенные миграции: они загружаются и идут без пропусков версий
+func TestAll(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(all) == 0 {
+		t.Fatal("встроенных миграций нет")
+	}
+	for i, m := range all {
+		if m.Version != int64(i+1) {
+			t.Errorf("миграция %04d_%s на позиции %d, ожидалась версия %d", m.Version, m.Name, i, i+1)
+		}
+	}
+}
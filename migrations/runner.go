@@ -0,0 +1,235 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// lockKey — ключ advisory lock, под которым применяются миграции.
+// Реплики, запущенные одновременно, применяют миграции по очереди.
+const lockKey int64 = 0x4c30_6d69_6772 // "L0migr"
+
+// ErrNotMigrated — в БД применены не все миграции, известные сервису
+var ErrNotMigrated = errors.New("схема БД не обновлена до последней миграции")
+
+// Status — состояние одной миграции в БД
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Unknown — версия применена в БД, но её нет в этом бинарнике (схема новее сервиса)
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Runner применяет и откатывает встроенные миграции.
+// Применённые версии хранятся в таблице schema_migrations.
+type Runner struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+// NewRunner создает исполнитель встроенных миграций
+func NewRunner(db *pgxpool.Pool) (*Runner, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{db: db, migrations: migrations}, nil
+}
+
+// Up применяет все неприменённые миграции по возрастанию версии и возвращает их.
+// Каждая миграция выполняется в своей транзакции вместе с записью в schema_migrations.
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := r.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			err := runInTx(ctx, conn, m.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+			if err != nil {
+				return fmt.Errorf("миграция %04d_%s: %w", m.Version, m.Name, err)
+			}
+			log.Printf("Применена миграция %04d_%s", m.Version, m.Name)
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down откатывает steps последних применённых миграций и возвращает их
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	known := make(map[int64]Migration, len(r.migrations))
+	for _, m := range r.migrations {
+		known[m.Version] = m
+	}
+
+	var done []Migration
+	err := r.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, v := range versions[:min(steps, len(versions))] {
+			m, ok := known[v]
+			if !ok {
+				return fmt.Errorf("миграция %04d применена в БД, но неизвестна этой версии сервиса", v)
+			}
+			err := runInTx(ctx, conn, m.Down, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+			if err != nil {
+				return fmt.Errorf("откат миграции %04d_%s: %w", m.Version, m.Name, err)
+			}
+			log.Printf("Откачена миграция %04d_%s", m.Version, m.Name)
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status возвращает состояние всех известных миграций, а также применённых
+// в БД версий, которых нет в этом бинарнике
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		st := Status{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			st.AppliedAt = &a.at
+			delete(applied, m.Version)
+		}
+		statuses = append(statuses, st)
+	}
+	for v, a := range applied {
+		at := a.at
+		statuses = append(statuses, Status{Version: v, Name: a.name, AppliedAt: &at, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Check возвращает ErrNotMigrated, если какая-либо известная миграция не применена.
+// Версии новее сервиса допускаются: так старые реплики работают во время выкладки.
+func (r *Runner) Check(ctx context.Context) error {
+	statuses, err := r.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			return fmt.Errorf("%w: не применена миграция %04d_%s", ErrNotMigrated, st.Version, st.Name)
+		}
+	}
+	return nil
+}
+
+// withLock выполняет fn на отдельном соединении под advisory lock.
+// Блокировка сессионная, поэтому все запросы fn идут через одно соединение.
+func (r *Runner) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("ошибка получения блокировки миграций: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			log.Printf("Ошибка снятия блокировки миграций: %v", err)
+		}
+	}()
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return fmt.Errorf("ошибка создания schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+// runInTx выполняет SQL миграции и запрос учёта версии в одной транзакции
+func runInTx(ctx context.Context, conn *pgxpool.Conn, migrationSQL, record string, args ...interface{}) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Без аргументов pgx использует простой протокол, допускающий несколько команд
+	if _, err := tx.Exec(ctx, migrationSQL); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+type appliedMigration struct {
+	name string
+	at   time.Time
+}
+
+// applied читает применённые версии без блокировки; если таблицы ещё нет,
+// миграции считаются неприменёнными
+func (r *Runner) applied(ctx context.Context) (map[int64]appliedMigration, error) {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return map[int64]appliedMigration{}, nil
+	}
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+	return appliedVersions(ctx, conn)
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.Query(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var v int64
+		var a appliedMigration
+		if err := rows.Scan(&v, &a.name, &a.at); err != nil {
+			return nil, err
+		}
+		applied[v] = a
+	}
+	return applied, rows.Err()
+}
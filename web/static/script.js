@@ -1,7 +1,9 @@
 document.getElementById("order-form").addEventListener("submit", async (e) => {
     e.preventDefault();
+    await showOrder(document.getElementById("order-id").value.trim());
+});
 
-    const orderId = document.getElementById("order-id").value.trim();
+async function showOrder(orderId) {
     const resultDiv = document.getElementById("result");
 
     resultDiv.textContent = "Загрузка...";
@@ -17,4 +19,63 @@ document.getElementById("order-form").addEventListener("submit", async (e) => {
     } catch (err) {
         resultDiv.textContent = `Ошибка запроса: ${err.message}`;
     }
+}
+
+document.getElementById("search-form").addEventListener("submit", async (e) => {
+    e.preventDefault();
+
+    const query = document.getElementById("search-query").value.trim();
+    const list = document.getElementById("search-results");
+
+    list.textContent = "Поиск...";
+
+    try {
+        const response = await fetch(`/orders/search?q=${encodeURIComponent(query)}`);
+        if (!response.ok) {
+            list.textContent = `Ошибка: ${response.status} ${response.statusText}`;
+            return;
+        }
+        const data = await response.json();
+        list.textContent = "";
+        if (data.results.length === 0) {
+            list.textContent = "Ничего не найдено";
+            return;
+        }
+        for (const res of data.results) {
+            list.appendChild(renderSearchResult(res));
+        }
+    } catch (err) {
+        list.textContent = `Ошибка запроса: ${err.message}`;
+    }
 });
+
+// renderSearchResult строит элемент списка без innerHTML: подсветка <mark>
+// из ответа превращается в элементы, остальной текст вставляется как текст
+function renderSearchResult(res) {
+    const li = document.createElement("li");
+
+    const link = document.createElement("a");
+    link.href = "#";
+    link.textContent = res.order.order_uid;
+    link.addEventListener("click", (e) => {
+        e.preventDefault();
+        document.getElementById("order-id").value = res.order.order_uid;
+        showOrder(res.order.order_uid);
+    });
+    li.appendChild(link);
+
+    const snippet = document.createElement("div");
+    snippet.className = "snippet";
+    res.highlight.split(/(<mark>.*?<\/mark>)/).forEach((part) => {
+        if (part.startsWith("<mark>")) {
+            const mark = document.createElement("mark");
+            mark.textContent = part.slice(6, -7);
+            snippet.appendChild(mark);
+        } else if (part) {
+            snippet.appendChild(document.createTextNode(part));
+        }
+    });
+    li.appendChild(snippet);
+
+    return li;
+}